@@ -329,7 +329,7 @@ func main() {
 		}
 	}()
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	fmt.Printf("Waiting for Ctrl-C\n")
 	select {
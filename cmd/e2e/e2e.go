@@ -296,6 +296,21 @@ func main() {
 			Daddr:      "2001:1::2/64",
 			Validation: validations.ICMPDropTestValidation,
 		},
+		{
+			// Rules are programmed by the validation, the chain is bound to the veth interface created for the test
+			Name:       "IPV4 Netdev ICMP Drop",
+			Version:    nftables.TableFamilyIPv4,
+			Saddr:      "1.1.1.1/24",
+			Daddr:      "1.1.1.2/24",
+			Validation: validations.NetdevICMPDropValidation,
+		},
+		{
+			Name:       "IPV6 Netdev ICMP Drop",
+			Version:    nftables.TableFamilyIPv6,
+			Saddr:      "2001:1::1/64",
+			Daddr:      "2001:1::2/64",
+			Validation: validations.NetdevICMPDropValidation,
+		},
 	}
 
 	memProf, err := os.Create("/tmp/heap.out")
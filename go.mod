@@ -1,16 +1,20 @@
 module github.com/sbezverk/nftableslib
 
-go 1.17
+go 1.21
 
 require (
 	github.com/google/gopacket v1.1.17
-	github.com/google/nftables v0.0.0-20221002140148-535f5eb8da79
+	github.com/google/nftables v0.3.0
 	github.com/google/uuid v1.3.0
-	github.com/koneu/natend v0.0.0-20150829182554-ec0926ea948d // indirect
-	github.com/mdlayher/netlink v1.6.2 // indirect
-	github.com/vishvananda/netlink v1.0.0
-	github.com/vishvananda/netns v0.0.0-20190625233234-7109fa855b0f
-	golang.org/x/net v0.0.0-20221004154528-8021a29435af
-	golang.org/x/sync v0.0.0-20220929204114-8fcdb60fdcc0 // indirect
-	golang.org/x/sys v0.0.0-20221010170243-090e33056c14
+	github.com/mdlayher/netlink v1.7.3-0.20250113171957-fbb4dce95f42
+	github.com/vishvananda/netlink v1.3.0
+	github.com/vishvananda/netns v0.0.4
+	golang.org/x/net v0.33.0
+	golang.org/x/sys v0.28.0
+)
+
+require (
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/mdlayher/socket v0.5.0 // indirect
+	golang.org/x/sync v0.6.0 // indirect
 )
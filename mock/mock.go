@@ -114,6 +114,25 @@ func (m *Mock) SetDeleteElements(set *nftables.Set, elements []nftables.SetEleme
 	return nil
 }
 
+// AddObj not used
+func (m *Mock) AddObj(o nftables.Obj) nftables.Obj {
+	return o
+}
+
+// DeleteObject not used
+func (m *Mock) DeleteObject(o nftables.Obj) {
+}
+
+// GetNamedObjects not implemented yet
+func (m *Mock) GetNamedObjects(t *nftables.Table) ([]nftables.Obj, error) {
+	return nil, nil
+}
+
+// ResetObject not implemented yet
+func (m *Mock) ResetObject(o nftables.Obj) (nftables.Obj, error) {
+	return nil, nil
+}
+
 // InitMockConn initializes mock connection of the nftables family
 func InitMockConn() *Mock {
 	m := &Mock{}
@@ -1,9 +1,13 @@
 package mock
 
 import (
+	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
 	"github.com/sbezverk/nftableslib"
 	"golang.org/x/sys/unix"
 )
@@ -32,6 +36,54 @@ func setActionReject(t *testing.T, rejectType, rejectCode int) *nftableslib.Rule
 	return ra
 }
 
+func setActionQueue(t *testing.T, queueAttrs *nftableslib.QueueAttributes) *nftableslib.RuleAction {
+	ra, err := nftableslib.SetQueue(queueAttrs)
+	if err != nil {
+		t.Fatalf("failed to SetQueue with error: %+v", err)
+	}
+	return ra
+}
+
+func setActionDup(t *testing.T, addr string, dev uint32) *nftableslib.RuleAction {
+	var ip *nftableslib.IPAddr
+	if addr != "" {
+		var err error
+		ip, err = nftableslib.NewIPAddr(addr)
+		if err != nil {
+			t.Fatalf("failed to parse address %s with error: %+v", addr, err)
+		}
+	}
+	ra, err := nftableslib.SetDup(ip, dev)
+	if err != nil {
+		t.Fatalf("failed to SetDup with error: %+v", err)
+	}
+	return ra
+}
+
+func setActionTyped(t *testing.T, set func() (*nftableslib.RuleAction, error)) *nftableslib.RuleAction {
+	ra, err := set()
+	if err != nil {
+		t.Fatalf("failed to set action with error: %+v", err)
+	}
+	return ra
+}
+
+func setActionCTMark(t *testing.T, value, mask uint32) *nftableslib.RuleAction {
+	ra, err := nftableslib.SetCTMark(value, mask)
+	if err != nil {
+		t.Fatalf("failed to SetCTMark with error: %+v", err)
+	}
+	return ra
+}
+
+func setActionCTZoneByInterface(t *testing.T, zones map[string]uint16) *nftableslib.RuleAction {
+	ra, err := nftableslib.SetCTZoneByInterface(zones)
+	if err != nil {
+		t.Fatalf("failed to SetCTZoneByInterface with error: %+v", err)
+	}
+	return ra
+}
+
 func setIPAddr(t *testing.T, addr string) *nftableslib.IPAddr {
 	a, err := nftableslib.NewIPAddr(addr)
 	if err != nil {
@@ -40,6 +92,22 @@ func setIPAddr(t *testing.T, addr string) *nftableslib.IPAddr {
 	return a
 }
 
+func setMACAddr(t *testing.T, addr string) net.HardwareAddr {
+	mac, err := nftableslib.NewMACAddr(addr)
+	if err != nil {
+		t.Fatalf("error %+v return from NewMACAddr for address: %s", err, addr)
+	}
+	return mac
+}
+
+func setUint16(v uint16) *uint16 {
+	return &v
+}
+
+func setUint8(v uint8) *uint8 {
+	return &v
+}
+
 func setLog(key int, value []byte) *nftableslib.Log {
 	log, _ := nftableslib.SetLog(key, value)
 	return log
@@ -127,405 +195,2048 @@ func TestMock(t *testing.T) {
 		success bool
 	}{
 		{
-			name: "L3 redirect proto no TProxy",
+			name: "Meta set priority and nftrace",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst:     &nftableslib.Port{List: nftableslib.SetPortList([]int{22})},
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Meta: &nftableslib.Meta{
+					Set: []nftableslib.MetaSet{
+						{Key: unix.NFT_META_PRIORITY, Value: nftableslib.TCHandle(1, 0x10)},
+						{Key: unix.NFT_META_NFTRACE, Value: []byte{1}},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
 			},
 			success: true,
 		},
 		{
-			name: "L3 redirect proto with TProxy",
+			name: "Meta set priority by map and secmark",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				Meta: &nftableslib.Meta{
+					Set: []nftableslib.MetaSet{
+						{
+							Key: unix.NFT_META_PRIORITY,
+							Map: &nftableslib.MetaSetMap{
+								Key:    []*nftableslib.ConcatElement{{EType: nftables.TypeIPAddr}},
+								SetRef: &nftableslib.SetRef{Name: "classes", ID: 1, IsMap: true},
+							},
+						},
+						{Key: unix.NFT_META_SECMARK, Object: "sshtag"},
+					},
 				},
-				Action: setActionRedirect(t, portRedirect, true),
 			},
 			success: true,
 		},
 		{
-			name: "Single IPv4 in list, source, no exclusion, with subnet mask",
+			name: "Meta set secmark by value",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Src: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "192.16.0.0/16"), setIPAddr(t, "10.16.0.0/16")},
-					},
+				Meta: &nftableslib.Meta{
+					Set: []nftableslib.MetaSet{{Key: unix.NFT_META_SECMARK, Value: []byte{1, 0, 0, 0}}},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "Single IPv4 in list, source, no exclusion",
+			name: "Loadbalance by hash of source address and port",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Src: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.0")},
-					},
-				},
-				Action: setActionReject(t, unix.NFT_REJECT_ICMPX_UNREACH, unix.NFT_REJECT_ICMPX_HOST_UNREACH),
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetLoadbalanceHash([]string{"fake-chain-1", "fake-chain-2"}, []int{3, 1}, unix.NFT_JUMP,
+						&nftableslib.LoadbalanceHash{
+							Fields: []*nftableslib.ConcatElement{
+								{EType: nftables.TypeIPAddr, ESource: true},
+								{EType: nftables.TypeInetService, ESource: true},
+							},
+							Seed: 0xcafe,
+						})
+				}),
 			},
 			success: true,
 		},
 		{
-			name: "Single IPv4 in list, destination, exclusion",
+			name: "DNAT to address and port selected by concatenated map",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1")},
-					},
-					RelOp: nftableslib.NEQ,
-				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetDNAT(&nftableslib.NATAttributes{
+						Map: &nftableslib.NATMap{
+							Key: []*nftableslib.ConcatElement{
+								{EType: nftables.TypeIPAddr},
+								{EType: nftables.TypeInetService, EProto: unix.IPPROTO_TCP},
+							},
+							SetRef:   &nftableslib.SetRef{Name: "fake-svc-map", IsMap: true},
+							DataType: nftables.MustConcatSetType(nftables.TypeIPAddr, nftables.TypeInetService),
+						},
+					})
+				}),
 			},
 			success: true,
 		},
 		{
-			name: "Multiple IPv4s in list, source, exclusion",
+			name: "SNAT to pool of addresses",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1"), setIPAddr(t, "192.0.3.1"), setIPAddr(t, "192.0.4.1")},
-					},
-					RelOp: nftableslib.NEQ,
-				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetSNAT(&nftableslib.NATAttributes{
+						Pool: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1"), setIPAddr(t, "192.0.2.2")},
+					})
+				}),
 			},
 			success: true,
 		},
 		{
-			name: "Multiple IPv4s in list, destination, no exclusion",
+			name: "DNAT map with ipv6 data in ipv4 table",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1"), setIPAddr(t, "192.0.3.1"), setIPAddr(t, "192.0.4.1")},
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetDNAT(&nftableslib.NATAttributes{
+						Map: &nftableslib.NATMap{
+							Key:      []*nftableslib.ConcatElement{{EType: nftables.TypeIPAddr, ESource: true}},
+							SetRef:   &nftableslib.SetRef{Name: "fake-svc-map", IsMap: true},
+							DataType: nftables.TypeIP6Addr,
+						},
+					})
+				}),
+			},
+			success: false,
+		},
+		{
+			name: "TCP reset of ssh connections",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{22}),
 					},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionTyped(t, nftableslib.SetRejectTCPReset),
 			},
 			success: true,
 		},
 		{
-			name: "IPv4 Range, destination, no exclusion",
+			name: "TCP reset without tcp match",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Src: &nftableslib.IPAddrSpec{
-						Range: [2]*nftableslib.IPAddr{setIPAddr(t, "1.1.1.0"), setIPAddr(t, "2.2.2.0")},
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_UDP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{53}),
 					},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionTyped(t, nftableslib.SetRejectTCPReset),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "IPv4 Range, destination, no exclusion",
+			name: "ICMPv6 reject in ipv4 table",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
-				},
-				Action: setSNAT(t, &nftableslib.NATAttributes{
-					L3Addr: [2]*nftableslib.IPAddr{setIPAddr(t, "5.5.5.5")},
-					Port:   [2]uint16{7777},
-				})},
-			success: true,
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetRejectICMPv6(nftableslib.ICMPv6RejectAdminProhibited)
+				}),
+			},
+			success: false,
 		},
-	}
-	ipv6Tests := []struct {
-		name    string
-		rule    nftableslib.Rule
-		success bool
-	}{
 		{
-			name: "Single IPv6 in list, source, no exclusion",
+			name: "Dup tcp traffic to ids address via interface",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Src: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{80}),
 					},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionDup(t, "192.0.2.10", 2),
 			},
 			success: true,
 		},
 		{
-			name: "Single IPv6 in list, source, no exclusion, CIDR",
+			name: "Dup without address in ipv4 table",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Src: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "::1/128")},
-					},
-				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionDup(t, "", 2),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "Single IPv6 in list, source, no exclusion, with subnet mask ",
+			name: "Dup to ipv6 address in ipv4 table",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Src: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::/64")},
-					},
-				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionDup(t, "2001:db8::10", 0),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "Single IPv6 in list, destination, exclusion",
+			name: "Queue udp traffic to range of queues",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "fe80::1852:15be:a31d:5d2f")},
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_UDP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{53}),
 					},
-					RelOp: nftableslib.NEQ,
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionQueue(t, &nftableslib.QueueAttributes{Num: [2]uint16{0, 3}, Bypass: true, Fanout: true}),
 			},
 			success: true,
 		},
 		{
-			name: "Multiple IPv6s in list, source, exclusion",
+			name: "Rule referencing named counter and quota",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1"), setIPAddr(t, "fe80::1852:15be:a31d:5d2f")},
-					},
-					RelOp: nftableslib.NEQ,
+				ObjectRefs: []*nftableslib.ObjectRef{
+					{Type: nftableslib.ObjectTypeCounter, Name: "tenant-1"},
+					{Type: nftableslib.ObjectTypeQuota, Name: "tenant-1"},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
 			success: true,
 		},
 		{
-			name: "Multiple IPv6s in list, destination, no exclusion",
+			name: "Rule referencing named object without name",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						List: []*nftableslib.IPAddr{
-							setIPAddr(t, "2001:470:b87e:81::11"),
-							setIPAddr(t, "fe80::5054:ff:fe6c:1c4d"),
-							setIPAddr(t, "fe80::5054:ff:fecd:2379"),
-						},
-					},
+				ObjectRefs: []*nftableslib.ObjectRef{
+					{Type: nftableslib.ObjectTypeCounter},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "IPv6 Range, destination, no exclusion",
+			name: "Dynamic meter limiting rate per source address",
 			rule: nftableslib.Rule{
-				L3: &nftableslib.L3Rule{
-					Dst: &nftableslib.IPAddrSpec{
-						Range: [2]*nftableslib.IPAddr{
-							setIPAddr(t, "2001:470:b87e:81::11"),
-							setIPAddr(t, "2001:470:b87e:89::11"),
-						},
+				Dynamic: &nftableslib.Dynamic{
+					Match:   nftableslib.MatchTypeL3Src,
+					Op:      unix.NFT_DYNSET_OP_UPDATE,
+					SetRef:  &nftableslib.SetRef{Name: "fake-meter-1"},
+					Timeout: time.Minute,
+					Counter: &nftableslib.Counter{},
+					Limit: &nftableslib.Limit{
+						Rate: 10,
+						Unit: nftableslib.LimitPerSecond,
+						Over: true,
 					},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake_chain_1"),
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
 			success: true,
 		},
-	}
-
-	l4PortTests := []struct {
-		name    string
-		rule    nftableslib.Rule
-		success bool
-	}{
 		{
-			name: "L4 Single source port with verdict",
+			name: "Dynamic meter limiting connections per source address",
 			rule: nftableslib.Rule{
 				L4: &nftableslib.L4Rule{
 					L4Proto: unix.IPPROTO_TCP,
-					Src: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1}),
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{22}),
 					},
 				},
-				Action: setActionVerdict(t, unix.NFT_JUMP, "fake_chain_1"),
+				Dynamic: &nftableslib.Dynamic{
+					Match:     nftableslib.MatchTypeL3Src,
+					Op:        unix.NFT_DYNSET_OP_ADD,
+					SetRef:    &nftableslib.SetRef{Name: "fake-meter-1"},
+					ConnLimit: &nftableslib.ConnLimit{Count: 20, Over: true},
+				},
+				Action: setActionReject(t, unix.NFT_REJECT_TCP_RST, 0),
 			},
 			success: true,
 		},
 		{
-			name: "L4 Single destination port with verdict",
+			name: "Dynamic meter with connlimit count 0",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_UDP,
-					Src: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port2}),
-					},
+				Dynamic: &nftableslib.Dynamic{
+					Match:     nftableslib.MatchTypeL3Src,
+					Op:        unix.NFT_DYNSET_OP_ADD,
+					SetRef:    &nftableslib.SetRef{Name: "fake-meter-1"},
+					ConnLimit: &nftableslib.ConnLimit{},
 				},
-				Action: setActionVerdict(t, unix.NFT_RETURN),
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "L4 Single destination port with verdict and exclusion",
+			name: "Limit of new ssh connections",
 			rule: nftableslib.Rule{
 				L4: &nftableslib.L4Rule{
 					L4Proto: unix.IPPROTO_TCP,
 					Dst: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1}),
+						List: nftableslib.SetPortList([]int{22}),
 					},
-					RelOp: nftableslib.NEQ,
 				},
-				Action: setActionVerdict(t, unix.NFT_RETURN),
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_STATE,
+						Value: binaryutil.BigEndian.PutUint32(nftableslib.CTStateNew),
+					},
+				},
+				Limit: &nftableslib.Limit{
+					Rate:  10,
+					Unit:  nftableslib.LimitPerMinute,
+					Burst: 5,
+					Over:  true,
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
 			success: true,
 		},
 		{
-			name: "L4 Single source port with redirect",
+			name: "Limit of bytes and quota",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Src: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1}),
-					},
+				Limit: &nftableslib.Limit{
+					Rate:  1048576,
+					Unit:  nftableslib.LimitPerSecond,
+					Bytes: true,
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Quota: &nftableslib.Quota{
+					Bytes:    1073741824,
+					Consumed: 1024,
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
 			},
 			success: true,
 		},
 		{
-			name: "L4 Single destination port with redirect",
+			name: "Limit with rate 0",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_UDP,
-					Dst: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1}),
-					},
+				Limit: &nftableslib.Limit{
+					Unit: nftableslib.LimitPerSecond,
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "L4 Single destination port with redirect and exclusion",
+			name: "Quota of 0 bytes",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Dst: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1}),
-					},
-					RelOp: nftableslib.NEQ,
-				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Quota:  &nftableslib.Quota{Over: true},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
-			success: true,
+			success: false,
 		},
 		{
-			name: "L4 list of destination ports with redirects",
+			name: "Conntrack set masked mark",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Dst: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1, port2, port3}),
-					},
+				Meta: &nftableslib.Meta{
+					Mark: &nftableslib.MetaMark{Value: 0x1},
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Action: setActionCTMark(t, 0x100, 0xff00),
 			},
 			success: true,
 		},
 		{
-			name: "L4 list of destination ports with verdicts",
+			name: "Conntrack set zone by input interface",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Dst: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1, port2}),
-					},
-				},
-				Action: setActionVerdict(t, unix.NFT_RETURN),
+				Action: setActionCTZoneByInterface(t, map[string]uint16{"eth0": 1, "eth1": 2}),
 			},
 			success: true,
 		},
 		{
-			name: "L4 list of destination ports with redirects with exclude",
+			name: "Conntrack status dnat and direction reply",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Dst: &nftableslib.Port{
-						List: nftableslib.SetPortList([]int{port1, port2}),
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_STATUS,
+						Value: binaryutil.BigEndian.PutUint32(nftableslib.CTStatusDNAT),
+					},
+					{
+						Key:   unix.NFT_CT_DIRECTION,
+						Value: []byte{nftableslib.CTDirectionReply},
 					},
-					RelOp: nftableslib.NEQ,
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
 			},
 			success: true,
 		},
 		{
-			name: "L4 list of destination ports with verdicts with exclude",
+			name: "Conntrack masked mark greater than, zone and expiration less than",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_MARK,
+						Value: binaryutil.NativeEndian.PutUint32(0x10),
+						Mask:  binaryutil.NativeEndian.PutUint32(0xff),
+						RelOp: nftableslib.GT,
+					},
+					{
+						Key:   unix.NFT_CT_ZONE,
+						Value: binaryutil.NativeEndian.PutUint16(2),
+					},
+					{
+						Key:   unix.NFT_CT_EXPIRATION,
+						Value: binaryutil.NativeEndian.PutUint32(5000),
+						RelOp: nftableslib.LT,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Conntrack original masked destination address and reply source port",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_DST,
+						Value: net.ParseIP("10.0.0.0").To4(),
+						Mask:  net.ParseIP("255.0.0.0").To4(),
+						RelOp: nftableslib.NEQ,
+					},
+					{
+						Key:       unix.NFT_CT_PROTO_SRC,
+						Direction: nftableslib.CTDirectionReply,
+						Value:     binaryutil.BigEndian.PutUint16(8080),
+						RelOp:     nftableslib.GTE,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Conntrack status with relational operator less than",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_STATUS,
+						Value: binaryutil.BigEndian.PutUint32(nftableslib.CTStatusSNAT),
+						RelOp: nftableslib.LT,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "Conntrack mark of reply direction",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:       unix.NFT_CT_MARK,
+						Value:     binaryutil.NativeEndian.PutUint32(1),
+						Direction: nftableslib.CTDirectionReply,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "Conntrack eventmask",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_EVENTMASK,
+						Value: binaryutil.NativeEndian.PutUint32(1),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "IPv4 protocol not equal",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+					RelOp:    nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Meta mark greater than and conntrack state not established",
+			rule: nftableslib.Rule{
+				Meta: &nftableslib.Meta{
+					Mark: &nftableslib.MetaMark{Value: 0x100, Mask: 0xff00, RelOp: nftableslib.GT},
+				},
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_STATE,
+						Value: binaryutil.BigEndian.PutUint32(nftableslib.CTStateEstablished),
+						RelOp: nftableslib.NEQ,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Meta length less or equal",
+			rule: nftableslib.Rule{
+				Meta: &nftableslib.Meta{
+					Expr: []nftableslib.MetaExpr{
+						{
+							Key:   unix.NFT_META_LEN,
+							Value: binaryutil.NativeEndian.PutUint32(64),
+							RelOp: nftableslib.LTE,
+						},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Meta protocol greater than",
+			rule: nftableslib.Rule{
+				Meta: &nftableslib.Meta{
+					Expr: []nftableslib.MetaExpr{
+						{
+							Key:   unix.NFT_META_PROTOCOL,
+							Value: binaryutil.BigEndian.PutUint16(unix.ETH_P_IP),
+							RelOp: nftableslib.GT,
+						},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Fib address type less than",
+			rule: nftableslib.Rule{
+				Fib: &nftableslib.Fib{
+					ResultADDRTYPE: true,
+					FlagDADDR:      true,
+					Data:           binaryutil.NativeEndian.PutUint32(unix.RTN_LOCAL),
+					RelOp:          nftableslib.LT,
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "List of ports greater than",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List:  nftableslib.SetPortList([]int{80, 443}),
+						RelOp: nftableslib.GT,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "IPv4 DSCP EF and ECN",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					DSCP: &nftableslib.HeaderField{Value: 46},
+					ECN:  &nftableslib.HeaderField{Value: 3, RelOp: nftableslib.NEQ},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "IPv4 TTL and length",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					TTL:    &nftableslib.HeaderField{Value: 2, RelOp: nftableslib.LT},
+					Length: &nftableslib.HeaderField{Value: 1500, RelOp: nftableslib.GT},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "IPv4 fragments",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					FragOffset:    &nftableslib.HeaderField{Value: 0},
+					MoreFragments: func() *bool { b := true; return &b }(),
+					ID:            &nftableslib.HeaderField{Value: 12345},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "IPv4 DSCP out of range",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					DSCP: &nftableslib.HeaderField{Value: 64},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "IPv4 flow label",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					FlowLabel: &nftableslib.HeaderField{Value: 1},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "TCP MSS clamping to path MTU",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					TCPFlags: &nftableslib.TCPFlags{
+						Mask:  nftableslib.TCPFlagSYN | nftableslib.TCPFlagRST,
+						Value: nftableslib.TCPFlagSYN,
+					},
+				},
+				TCPOption: &nftableslib.TCPOption{
+					SetMSSToPMTU: true,
+				},
+			},
+			success: true,
+		},
+		{
+			name: "TCP MSS set to constant value",
+			rule: nftableslib.Rule{
+				TCPOption: &nftableslib.TCPOption{
+					MSS:    setUint16(1460),
+					SetMSS: setUint16(1360),
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "TCP without SACK permitted option",
+			rule: nftableslib.Rule{
+				TCPOption: &nftableslib.TCPOption{
+					Kind:  setUint8(nftableslib.TCPOptionSACKPermitted),
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "TCP MSS set to constant value and path MTU",
+			rule: nftableslib.Rule{
+				TCPOption: &nftableslib.TCPOption{
+					SetMSS:       setUint16(1360),
+					SetMSSToPMTU: true,
+				},
+			},
+			success: false,
+		},
+		{
+			name: "TCP option kind and MSS",
+			rule: nftableslib.Rule{
+				TCPOption: &nftableslib.TCPOption{
+					Kind: setUint8(nftableslib.TCPOptionMSS),
+					MSS:  setUint16(1460),
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "TCP SYN only",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					TCPFlags: &nftableslib.TCPFlags{
+						Mask:  nftableslib.TCPFlagSYN | nftableslib.TCPFlagACK,
+						Value: nftableslib.TCPFlagSYN,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "TCP NULL scan",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto:  unix.IPPROTO_TCP,
+					TCPFlags: &nftableslib.TCPFlags{Value: 0},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "TCP invalid flag combinations with destination port",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{80}),
+					},
+					TCPFlags: &nftableslib.TCPFlags{
+						Mask: nftableslib.TCPFlagFIN | nftableslib.TCPFlagSYN | nftableslib.TCPFlagRST |
+							nftableslib.TCPFlagPSH | nftableslib.TCPFlagURG,
+						List: []uint8{
+							nftableslib.TCPFlagFIN | nftableslib.TCPFlagPSH | nftableslib.TCPFlagURG,
+							nftableslib.TCPFlagSYN | nftableslib.TCPFlagFIN,
+							nftableslib.TCPFlagSYN | nftableslib.TCPFlagRST,
+						},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "TCP flags with udp protocol",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto:  unix.IPPROTO_UDP,
+					TCPFlags: &nftableslib.TCPFlags{Value: nftableslib.TCPFlagSYN},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "TCP flags value outside of mask",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					TCPFlags: &nftableslib.TCPFlags{Mask: nftableslib.TCPFlagSYN, Value: nftableslib.TCPFlagACK},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "ICMP echo request",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{Type: []uint8{8}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "ICMP list of types with exclude",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{Type: []uint8{0, 3, 8}, RelOp: nftableslib.NEQ},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "ICMP destination unreachable with port unreachable code",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_ICMP,
+					ICMP:    &nftableslib.ICMP{Type: []uint8{3}, Code: setUint8(3)},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "ICMP type and code with exclude",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_ICMP,
+					ICMP:    &nftableslib.ICMP{Type: []uint8{3}, Code: setUint8(3), RelOp: nftableslib.NEQ},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "ICMP code with exclude",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_ICMP,
+					ICMP:    &nftableslib.ICMP{Code: setUint8(3), RelOp: nftableslib.NEQ},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "ICMP types from a set",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{SetRef: &nftableslib.SetRef{Name: "fake-icmp-set"}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "ICMP combined with port",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_ICMP,
+					ICMP:    &nftableslib.ICMP{Type: []uint8{8}},
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{22}),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "ICMPv6 protocol in ipv4 table",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_ICMPV6,
+					ICMP:    &nftableslib.ICMP{Type: []uint8{128}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "L3 redirect proto no TProxy",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L3 redirect proto with TProxy",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				},
+				Action: setActionRedirect(t, portRedirect, true),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv4 in list, source, no exclusion, with subnet mask",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.16.0.0/16"), setIPAddr(t, "10.16.0.0/16")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv4 in list, source, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.0")},
+					},
+				},
+				Action: setActionReject(t, unix.NFT_REJECT_ICMPX_UNREACH, unix.NFT_REJECT_ICMPX_HOST_UNREACH),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv4 in list, destination, exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1")},
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Multiple IPv4s in list, source, exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1"), setIPAddr(t, "192.0.3.1"), setIPAddr(t, "192.0.4.1")},
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Multiple IPv4s in list, destination, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1"), setIPAddr(t, "192.0.3.1"), setIPAddr(t, "192.0.4.1")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "IPv4 Range, destination, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						Range: [2]*nftableslib.IPAddr{setIPAddr(t, "1.1.1.0"), setIPAddr(t, "2.2.2.0")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "IPv4 Range, destination, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				},
+				Action: setSNAT(t, &nftableslib.NATAttributes{
+					L3Addr: [2]*nftableslib.IPAddr{setIPAddr(t, "5.5.5.5")},
+					Port:   [2]uint16{7777},
+				})},
+			success: true,
+		},
+	}
+	ipv6Tests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
+		{
+			name: "TProxy to ipv6 address and port with mark",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst:     &nftableslib.Port{List: nftableslib.SetPortList([]int{80})},
+				},
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetTProxy(&nftableslib.TProxyAttributes{
+						Addr: setIPAddr(t, "::1"),
+						Port: 15001,
+						Mark: &nftableslib.MetaMark{Value: 1},
+					})
+				}),
+			},
+			success: true,
+		},
+		{
+			name: "TProxy to ipv4 address in ipv6 table",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst:     &nftableslib.Port{List: nftableslib.SetPortList([]int{80})},
+				},
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetTProxy(&nftableslib.TProxyAttributes{
+						Addr: setIPAddr(t, "127.0.0.1"),
+						Port: 15001,
+					})
+				}),
+			},
+			success: false,
+		},
+		{
+			name: "Conntrack ipv4 address in ipv6 table",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_DST,
+						Value: net.ParseIP("192.168.1.1").To4(),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "IPv6 traffic class and hop limit",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					TrafficClass: &nftableslib.HeaderField{Value: 0xb8},
+					TTL:          &nftableslib.HeaderField{Value: 64, RelOp: nftableslib.GTE},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "IPv6 fragment offset",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					FragOffset: &nftableslib.HeaderField{Value: 0, RelOp: nftableslib.NEQ},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "IPv6 drop RH0",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					ExtHdr: &nftableslib.IPv6ExtHdr{
+						Type:        nftableslib.IPv6ExtHdrRouting,
+						RoutingType: setUint8(nftableslib.IPv6RoutingType0),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "IPv6 without hop-by-hop options",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					ExtHdr: &nftableslib.IPv6ExtHdr{
+						Type:  nftableslib.IPv6ExtHdrHopByHop,
+						RelOp: nftableslib.NEQ,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "IPv6 first fragment",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					ExtHdr: &nftableslib.IPv6ExtHdr{
+						Type:           nftableslib.IPv6ExtHdrFragment,
+						FragmentOffset: setUint16(0),
+						MoreFragments:  func() *bool { b := true; return &b }(),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "IPv6 segments left in fragment header",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					ExtHdr: &nftableslib.IPv6ExtHdr{
+						Type:         nftableslib.IPv6ExtHdrFragment,
+						SegmentsLeft: []uint8{1},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "ICMPv6 echo request and neighbor discovery",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{Type: []uint8{128, 135, 136}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "ICMPv6 code without type",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{Code: setUint8(4)},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv6 in list, source, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv6 in list, source, no exclusion, CIDR",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "::1/128")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv6 in list, source, no exclusion, with subnet mask ",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::/64")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Single IPv6 in list, destination, exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "fe80::1852:15be:a31d:5d2f")},
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Multiple IPv6s in list, source, exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1"), setIPAddr(t, "fe80::1852:15be:a31d:5d2f")},
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "Multiple IPv6s in list, destination, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{
+							setIPAddr(t, "2001:470:b87e:81::11"),
+							setIPAddr(t, "fe80::5054:ff:fe6c:1c4d"),
+							setIPAddr(t, "fe80::5054:ff:fecd:2379"),
+						},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake-chain-1"),
+			},
+			success: true,
+		},
+		{
+			name: "IPv6 Range, destination, no exclusion",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						Range: [2]*nftableslib.IPAddr{
+							setIPAddr(t, "2001:470:b87e:81::11"),
+							setIPAddr(t, "2001:470:b87e:89::11"),
+						},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake_chain_1"),
+			},
+			success: true,
+		},
+	}
+
+	inetTests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
+		{
+			name: "Socket mark and cgroupv2 by id",
+			rule: nftableslib.Rule{
+				Socket: &nftableslib.Socket{
+					Mark:     &nftableslib.HeaderField{Value: 0x10},
+					Cgroupv2: &nftableslib.SocketCgroup{ID: 8001, Level: 2},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Socket without keys",
+			rule: nftableslib.Rule{
+				Socket: &nftableslib.Socket{},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "TProxy of transparent socket to ipv4 address and port with mark",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst:     &nftableslib.Port{List: nftableslib.SetPortList([]int{80})},
+				},
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetTProxy(&nftableslib.TProxyAttributes{
+						Addr:        setIPAddr(t, "127.0.0.1"),
+						Port:        15001,
+						Mark:        &nftableslib.MetaMark{Value: 1},
+						Transparent: true,
+					})
+				}),
+			},
+			success: true,
+		},
+		{
+			name: "TProxy to port",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_UDP,
+					Dst:     &nftableslib.Port{List: nftableslib.SetPortList([]int{53})},
+				},
+				Action: setActionTyped(t, func() (*nftableslib.RuleAction, error) {
+					return nftableslib.SetTProxy(&nftableslib.TProxyAttributes{Port: 15053})
+				}),
+			},
+			success: true,
+		},
+		{
+			name: "Conntrack original source ipv4 address and reply source ipv6 address",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_SRC,
+						Value: net.ParseIP("192.168.1.1").To4(),
+					},
+					{
+						Key:       unix.NFT_CT_SRC,
+						Direction: nftableslib.CTDirectionReply,
+						Value:     net.ParseIP("2001:db8::1").To16(),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Conntrack address of invalid length",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_DST,
+						Value: []byte{192, 168, 1},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "Inet hop limit without ip version",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					TTL: &nftableslib.HeaderField{Value: 2, RelOp: nftableslib.LT},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Inet flow label",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					FlowLabel: &nftableslib.HeaderField{Value: 0x12345},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Inet SRv6 segments left",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					ExtHdr: &nftableslib.IPv6ExtHdr{
+						Type:         nftableslib.IPv6ExtHdrRouting,
+						RoutingType:  setUint8(nftableslib.IPv6RoutingTypeSRH),
+						SegmentsLeft: []uint8{0, 1},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Inet extension header with ipv4 address",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "1.1.1.1")},
+					},
+					ExtHdr: &nftableslib.IPv6ExtHdr{Type: nftableslib.IPv6ExtHdrHopByHop},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Inet ICMP with protocol derived from address",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+					},
+				},
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{Type: []uint8{128}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Inet ICMP with explicit protocol",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_ICMP,
+					ICMP:    &nftableslib.ICMP{Type: []uint8{8}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Inet ICMP without protocol",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					ICMP: &nftableslib.ICMP{Type: []uint8{8}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "Inet IPv4 source and IPv6 destination mix",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1")},
+					},
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: false,
+		},
+		{
+			name: "Inet IPv4 list of sources with protocol",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1"), setIPAddr(t, "192.0.3.1")},
+					},
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "Inet IPv6 range of destinations",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						Range: [2]*nftableslib.IPAddr{
+							setIPAddr(t, "2001:470:b87e:81::11"),
+							setIPAddr(t, "2001:470:b87e:89::11"),
+						},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake_chain_1"),
+			},
+			success: true,
+		},
+		{
+			name: "Inet protocol only",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_UDP),
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Inet set reference without ip version",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						SetRef: &nftableslib.SetRef{Name: "fake-set-1"},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Inet IPv6 SNAT",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_TCP),
+				},
+				Action: setSNAT(t, &nftableslib.NATAttributes{
+					L3Addr: [2]*nftableslib.IPAddr{setIPAddr(t, "2001:1234::1")},
+					Port:   [2]uint16{7777},
+				})},
+			success: true,
+		},
+		{
+			name: "Inet concatenation of IPv6 address and port",
+			rule: nftableslib.Rule{
+				Concat: &nftableslib.Concat{
+					Elements: []*nftableslib.ConcatElement{
+						{EType: nftables.TypeIP6Addr, ESource: true},
+						{EType: nftables.TypeInetService},
+					},
+					VMap:   true,
+					SetRef: &nftableslib.SetRef{Name: "fake-map-1", IsMap: true},
+				},
+			},
+			success: true,
+		},
+		{
+			name: "Inet dynamic set update without address family",
+			rule: nftableslib.Rule{
+				Dynamic: &nftableslib.Dynamic{
+					Match:  nftableslib.MatchTypeL3Src,
+					Op:     unix.NFT_DYNSET_OP_ADD,
+					SetRef: &nftableslib.SetRef{Name: "fake-set-1"},
+				},
+			},
+			success: false,
+		},
+	}
+
+	l4PortTests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
+		{
+			name: "L4 Single source port with verdict",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Src: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1}),
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_JUMP, "fake_chain_1"),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Single destination port with verdict",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_UDP,
+					Src: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port2}),
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Single destination port with verdict and exclusion",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1}),
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Single source port with redirect",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Src: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1}),
+					},
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Single destination port with redirect",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_UDP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1}),
+					},
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Single destination port with redirect and exclusion",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1}),
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 list of destination ports with redirects",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1, port2, port3}),
+					},
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 list of destination ports with verdicts",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1, port2}),
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "L4 list of destination ports with redirects with exclude",
 			rule: nftableslib.Rule{
 				L4: &nftableslib.L4Rule{
 					L4Proto: unix.IPPROTO_TCP,
 					Dst: &nftableslib.Port{
 						List: nftableslib.SetPortList([]int{port1, port2}),
 					},
-					RelOp: nftableslib.NEQ,
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 list of destination ports with verdicts with exclude",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{port1, port2}),
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Range of destination ports with redirects",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+					},
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Range of destination ports with verdicts",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "L4 range of destination ports with redirects with exclude",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionRedirect(t, portRedirect, false),
+			},
+			success: true,
+		},
+		{
+			name: "L4 Range of destination ports with verdicts with exclude",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+					},
+					RelOp: nftableslib.NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+	}
+	netdevTests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
+		{
+			name: "Netdev conntrack address",
+			rule: nftableslib.Rule{
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_SRC,
+						Value: net.ParseIP("192.0.2.1").To4(),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "Netdev IPv4 source",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1")},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Netdev IPv6 destination with protocol",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+					},
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_ICMPV6),
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Netdev protocol only",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Protocol: nftableslib.L3Protocol(unix.IPPROTO_UDP),
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "Netdev IPv4 source and IPv6 destination mix",
+			rule: nftableslib.Rule{
+				L3: &nftableslib.L3Rule{
+					Src: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "192.0.2.1")},
+					},
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: false,
+		},
+		{
+			name: "Netdev L4 destination port",
+			rule: nftableslib.Rule{
+				L4: &nftableslib.L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &nftableslib.Port{
+						List: nftableslib.SetPortList([]int{22}),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Netdev bridge name",
+			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					IBrName: "br0",
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Netdev source MAC",
+			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					Src: &nftableslib.MACAddrSpec{
+						List: []net.HardwareAddr{setMACAddr(t, "02:42:ac:11:00:02")},
+					},
 				},
-				Action: setActionVerdict(t, unix.NFT_RETURN),
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
 			success: true,
 		},
+	}
+	bridgeTests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
 		{
-			name: "L4 Range of destination ports with redirects",
+			name: "Bridge conntrack address",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Dst: &nftableslib.Port{
-						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+				Conntracks: []*nftableslib.Conntrack{
+					{
+						Key:   unix.NFT_CT_SRC,
+						Value: net.ParseIP("192.0.2.1").To4(),
 					},
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: false,
+		},
+		{
+			name: "Bridge single source MAC",
+			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					Src: &nftableslib.MACAddrSpec{
+						List: []net.HardwareAddr{setMACAddr(t, "02:42:ac:11:00:02")},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
 			success: true,
 		},
 		{
-			name: "L4 Range of destination ports with verdicts",
+			name: "Bridge list of destination MACs with exclude",
 			rule: nftableslib.Rule{
-				L4: &nftableslib.L4Rule{
-					L4Proto: unix.IPPROTO_TCP,
-					Dst: &nftableslib.Port{
-						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+				L2: &nftableslib.L2Rule{
+					Dst: &nftableslib.MACAddrSpec{
+						List:  []net.HardwareAddr{setMACAddr(t, "02:42:ac:11:00:02"), setMACAddr(t, "02:42:ac:11:00:03")},
+						RelOp: nftableslib.NEQ,
 					},
 				},
-				Action: setActionVerdict(t, unix.NFT_RETURN),
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
 			},
 			success: true,
 		},
 		{
-			name: "L4 range of destination ports with redirects with exclude",
+			name: "Bridge VLAN id and pcp with ether type",
+			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					VLAN: &nftableslib.VLAN{
+						ID:  setUint16(100),
+						PCP: setUint8(5),
+					},
+					EtherType: nftableslib.L2EtherType(unix.ETH_P_IP),
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Bridge input and output bridge names",
+			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					IBrName: "br0",
+					OBrName: "br1",
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Bridge source MAC with IPv4 destination and port",
 			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					Src: &nftableslib.MACAddrSpec{
+						List: []net.HardwareAddr{setMACAddr(t, "02:42:ac:11:00:02")},
+					},
+				},
+				L3: &nftableslib.L3Rule{
+					Dst: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "172.17.0.3")},
+					},
+				},
 				L4: &nftableslib.L4Rule{
 					L4Proto: unix.IPPROTO_TCP,
 					Dst: &nftableslib.Port{
-						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+						List: nftableslib.SetPortList([]int{80}),
 					},
-					RelOp: nftableslib.NEQ,
 				},
-				Action: setActionRedirect(t, portRedirect, false),
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
 			},
 			success: true,
 		},
 		{
-			name: "L4 Range of destination ports with verdicts with exclude",
+			name: "Bridge invalid VLAN id",
+			rule: nftableslib.Rule{
+				L2: &nftableslib.L2Rule{
+					VLAN: &nftableslib.VLAN{
+						ID: setUint16(4096),
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Bridge empty L2 rule",
+			rule: nftableslib.Rule{
+				L2:     &nftableslib.L2Rule{},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+	}
+	arpTests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
+		{
+			name: "ARP request operation",
 			rule: nftableslib.Rule{
+				ARP: &nftableslib.ARPRule{
+					Operation: nftableslib.ARPOperation(nftableslib.ARPOpRequest),
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "ARP reply with sender hardware and protocol addresses",
+			rule: nftableslib.Rule{
+				ARP: &nftableslib.ARPRule{
+					Operation: nftableslib.ARPOperation(nftableslib.ARPOpReply),
+					SHA: &nftableslib.MACAddrSpec{
+						List: []net.HardwareAddr{setMACAddr(t, "02:42:ac:11:00:02")},
+					},
+					SPA: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "172.17.0.2")},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "ARP target protocol addresses not in the list",
+			rule: nftableslib.Rule{
+				ARP: &nftableslib.ARPRule{
+					TPA: &nftableslib.IPAddrSpec{
+						List:  []*nftableslib.IPAddr{setIPAddr(t, "172.17.0.0/24"), setIPAddr(t, "10.0.0.1")},
+						RelOp: nftableslib.NEQ,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "ARP target hardware address from a set",
+			rule: nftableslib.Rule{
+				ARP: &nftableslib.ARPRule{
+					THA: &nftableslib.MACAddrSpec{
+						SetRef: &nftableslib.SetRef{Name: "fake-mac-set"},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "ARP with ipv6 sender address",
+			rule: nftableslib.Rule{
+				ARP: &nftableslib.ARPRule{
+					SPA: &nftableslib.IPAddrSpec{
+						List: []*nftableslib.IPAddr{setIPAddr(t, "2001:0101::1")},
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "ARP empty rule",
+			rule: nftableslib.Rule{
+				ARP:    &nftableslib.ARPRule{},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+	}
+	intfTests := []struct {
+		name    string
+		rule    nftableslib.Rule
+		success bool
+	}{
+		{
+			name: "Interface input name",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Input: &nftableslib.IntfSpec{Name: []string{"eth0"}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Interface input wildcard name with exclude and output name",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Input:  &nftableslib.IntfSpec{Name: []string{"eth*"}, RelOp: nftableslib.NEQ},
+					Output: &nftableslib.IntfSpec{Name: []string{"lo"}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Interface list of output names",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Output: &nftableslib.IntfSpec{Name: []string{"eth0", "eth1"}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Interface input indexes with L4 port",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Input: &nftableslib.IntfSpec{Index: []uint32{2, 3}},
+				},
 				L4: &nftableslib.L4Rule{
 					L4Proto: unix.IPPROTO_TCP,
 					Dst: &nftableslib.Port{
-						Range: nftableslib.SetPortRange([2]int{port1, port2}),
+						List: nftableslib.SetPortList([]int{22}),
 					},
-					RelOp: nftableslib.NEQ,
 				},
-				Action: setActionVerdict(t, unix.NFT_RETURN),
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Interface input group",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Input: &nftableslib.IntfSpec{Group: []uint32{10}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_ACCEPT),
+			},
+			success: true,
+		},
+		{
+			name: "Interface output names from a set",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Output: &nftableslib.IntfSpec{
+						SetRef: &nftableslib.SetRef{Name: "fake-intf-set"},
+						Key:    nftableslib.IntfKeyName,
+						RelOp:  nftableslib.NEQ,
+					},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: true,
+		},
+		{
+			name: "Interface wildcard name in a list",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Input: &nftableslib.IntfSpec{Name: []string{"eth*", "lo"}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+		{
+			name: "Interface both name and index",
+			rule: nftableslib.Rule{
+				Interface: &nftableslib.Interface{
+					Input: &nftableslib.IntfSpec{Name: []string{"eth0"}, Index: []uint32{2}},
+				},
+				Action: setActionVerdict(t, nftableslib.NFT_DROP),
+			},
+			success: false,
+		},
+	}
+	netdevChainTests := []struct {
+		name    string
+		attrs   nftableslib.ChainAttributes
+		success bool
+	}{
+		{
+			name: "Netdev chain bound to a device",
+			attrs: nftableslib.ChainAttributes{
+				Hook:     nftables.ChainHookIngress,
+				Type:     nftables.ChainTypeFilter,
+				Priority: nftables.ChainPriorityFilter,
+				Device:   "eth0",
+			},
+			success: true,
+		},
+		{
+			name: "Netdev chain bound to multiple devices",
+			attrs: nftableslib.ChainAttributes{
+				Hook:     nftables.ChainHookIngress,
+				Type:     nftables.ChainTypeFilter,
+				Priority: nftables.ChainPriorityFilter,
+				Devices:  []string{"eth0", "eth1"},
 			},
 			success: true,
 		},
+		{
+			name: "Netdev chain without a device",
+			attrs: nftableslib.ChainAttributes{
+				Hook:     nftables.ChainHookIngress,
+				Type:     nftables.ChainTypeFilter,
+				Priority: nftables.ChainPriorityFilter,
+			},
+			success: false,
+		},
+		{
+			name: "Netdev chain with nat type",
+			attrs: nftableslib.ChainAttributes{
+				Hook:     nftables.ChainHookIngress,
+				Type:     nftables.ChainTypeNAT,
+				Priority: nftables.ChainPriorityFilter,
+				Device:   "eth0",
+			},
+			success: false,
+		},
+		{
+			name: "Netdev chain with both device and devices",
+			attrs: nftableslib.ChainAttributes{
+				Hook:     nftables.ChainHookIngress,
+				Type:     nftables.ChainTypeFilter,
+				Priority: nftables.ChainPriorityFilter,
+				Device:   "eth0",
+				Devices:  []string{"eth1"},
+			},
+			success: false,
+		},
 	}
 	m := InitMockConn()
 	m.ti.Tables().Create("filter-v4", nftables.TableFamilyIPv4)
@@ -547,6 +2258,52 @@ func TestMock(t *testing.T) {
 	}
 	tblV6.Chains().Create("chain-1-v6", &chainAttrs)
 
+	m.ti.Tables().Create("filter-inet", nftables.TableFamilyINet)
+	tblInet, err := m.ti.Tables().Table("filter-inet", nftables.TableFamilyINet)
+	if err != nil {
+		t.Fatalf("failed to get chain interface for table filter-inet")
+	}
+	tblInet.Chains().Create("chain-1-inet", &chainAttrs)
+
+	m.ti.Tables().Create("filter-netdev", nftables.TableFamilyNetdev)
+	tblNetdev, err := m.ti.Tables().Table("filter-netdev", nftables.TableFamilyNetdev)
+	if err != nil {
+		t.Fatalf("failed to get chain interface for table filter-netdev")
+	}
+	for i, tt := range netdevChainTests {
+		err := tblNetdev.Chains().Create(fmt.Sprintf("chain-%d-netdev", i+1), &tt.attrs)
+		if err == nil && !tt.success {
+			t.Errorf("Test: %s should fail but succeeded", tt.name)
+		}
+		if err != nil && tt.success {
+			t.Errorf("Test: %s should succeed but fail with error: %v", tt.name, err)
+		}
+	}
+
+	m.ti.Tables().Create("filter-bridge", nftables.TableFamilyBridge)
+	tblBridge, err := m.ti.Tables().Table("filter-bridge", nftables.TableFamilyBridge)
+	if err != nil {
+		t.Fatalf("failed to get chain interface for table filter-bridge")
+	}
+	bridgeChainAttrs := nftableslib.ChainAttributes{
+		Hook:     nftables.ChainHookForward,
+		Type:     nftables.ChainTypeFilter,
+		Priority: nftables.ChainPriorityFilter,
+	}
+	tblBridge.Chains().Create("chain-1-bridge", &bridgeChainAttrs)
+
+	m.ti.Tables().Create("filter-arp", nftables.TableFamilyARP)
+	tblARP, err := m.ti.Tables().Table("filter-arp", nftables.TableFamilyARP)
+	if err != nil {
+		t.Fatalf("failed to get chain interface for table filter-arp")
+	}
+	arpChainAttrs := nftableslib.ChainAttributes{
+		Hook:     nftableslib.ChainHookARPIn,
+		Type:     nftables.ChainTypeFilter,
+		Priority: nftables.ChainPriorityFilter,
+	}
+	tblARP.Chains().Create("chain-1-arp", &arpChainAttrs)
+
 	for _, tt := range ipv4Tests {
 		ri, err := tblV4.Chains().Chain("chain-1-v4")
 		if err != nil {
@@ -575,6 +2332,62 @@ func TestMock(t *testing.T) {
 		}
 	}
 
+	for _, tt := range inetTests {
+		ri, err := tblInet.Chains().Chain("chain-1-inet")
+		if err != nil {
+			t.Fatalf("failed to get rules interface for chain chain-1-inet")
+		}
+		_, err = ri.Rules().Create(&tt.rule)
+		if err == nil && !tt.success {
+			t.Errorf("Test: %s should fail but succeeded", tt.name)
+		}
+		if err != nil && tt.success {
+			t.Errorf("Test: %s should succeed but fail with error: %v", tt.name, err)
+		}
+	}
+
+	for _, tt := range bridgeTests {
+		ri, err := tblBridge.Chains().Chain("chain-1-bridge")
+		if err != nil {
+			t.Fatalf("failed to get rules interface for chain chain-1-bridge")
+		}
+		_, err = ri.Rules().Create(&tt.rule)
+		if err == nil && !tt.success {
+			t.Errorf("Test: %s should fail but succeeded", tt.name)
+		}
+		if err != nil && tt.success {
+			t.Errorf("Test: %s should succeed but fail with error: %v", tt.name, err)
+		}
+	}
+
+	for _, tt := range arpTests {
+		ri, err := tblARP.Chains().Chain("chain-1-arp")
+		if err != nil {
+			t.Fatalf("failed to get rules interface for chain chain-1-arp")
+		}
+		_, err = ri.Rules().Create(&tt.rule)
+		if err == nil && !tt.success {
+			t.Errorf("Test: %s should fail but succeeded", tt.name)
+		}
+		if err != nil && tt.success {
+			t.Errorf("Test: %s should succeed but fail with error: %v", tt.name, err)
+		}
+	}
+
+	for _, tt := range netdevTests {
+		ri, err := tblNetdev.Chains().Chain("chain-1-netdev")
+		if err != nil {
+			t.Fatalf("failed to get rules interface for chain chain-1-netdev")
+		}
+		_, err = ri.Rules().Create(&tt.rule)
+		if err == nil && !tt.success {
+			t.Errorf("Test: %s should fail but succeeded", tt.name)
+		}
+		if err != nil && tt.success {
+			t.Errorf("Test: %s should succeed but fail with error: %v", tt.name, err)
+		}
+	}
+
 	for _, tt := range l4PortTests {
 		ri, err := tblV4.Chains().Chain("chain-1-v4")
 		if err != nil {
@@ -589,6 +2402,20 @@ func TestMock(t *testing.T) {
 		}
 	}
 
+	for _, tt := range intfTests {
+		ri, err := tblV4.Chains().Chain("chain-1-v4")
+		if err != nil {
+			t.Fatalf("failed to get rules interface for chain chain-1-v4")
+		}
+		_, err = ri.Rules().Create(&tt.rule)
+		if err == nil && !tt.success {
+			t.Errorf("Test: %s should fail but succeeded", tt.name)
+		}
+		if err != nil && tt.success {
+			t.Errorf("Test: %s should succeed but fail with error: %v", tt.name, err)
+		}
+	}
+
 	for _, tt := range v2ipv4tests {
 		ri, err := tblV4.Chains().Chain("chain-1-v4")
 		if err != nil {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
 	"golang.org/x/sys/unix"
 )
 
@@ -35,8 +36,14 @@ type ChainAttributes struct {
 	Type     nftables.ChainType
 	Hook     *nftables.ChainHook
 	Priority *nftables.ChainPriority
-	Device   string
-	Policy   *ChainPolicy
+	// Device defines a device netdev family base chain is bound to.
+	Device string
+	// Devices defines a list of devices netdev family base chain is bound to, it is mutually exclusive
+	// with Device. When more than one device is specified, the chain is created as a regular chain and
+	// for each device a base chain bound to it is created, the device's chain passes packets to the chain
+	// by jump verdict.
+	Devices []string
+	Policy  *ChainPolicy
 }
 
 // Validate validate attributes passed for a base chain creation
@@ -44,11 +51,63 @@ func (cha *ChainAttributes) Validate() error {
 	if cha.Type == "" {
 		return fmt.Errorf("base chain must have type set")
 	}
-	// TODO Add additional attributes validation
+	if cha.Device != "" && len(cha.Devices) != 0 {
+		return fmt.Errorf("either Device or Devices but not both can be specified")
+	}
+	for _, dev := range cha.Devices {
+		if dev == "" {
+			return fmt.Errorf("device name cannot be empty")
+		}
+	}
 
 	return nil
 }
 
+// Hooks of arp family base chains, nftables.ChainHookInput and nftables.ChainHookOutput
+// carry values of inet family hooks and cannot be used in arp table.
+var (
+	ChainHookARPIn  = nftables.ChainHookRef(0)
+	ChainHookARPOut = nftables.ChainHookRef(1)
+)
+
+// validateFamily checks that the attributes are compatible with the family of the table
+// the base chain is created in.
+func (cha *ChainAttributes) validateFamily(family nftables.TableFamily) error {
+	switch family {
+	case nftables.TableFamilyNetdev:
+		if cha.Hook == nil || (*cha.Hook != *nftables.ChainHookIngress && *cha.Hook != *nftables.ChainHookEgress) {
+			return fmt.Errorf("netdev base chain must have either ingress or egress hook")
+		}
+		if cha.Type != nftables.ChainTypeFilter {
+			return fmt.Errorf("netdev base chain must be of filter type")
+		}
+		if cha.Device == "" && len(cha.Devices) == 0 {
+			return fmt.Errorf("netdev base chain must be bound to a device")
+		}
+		return nil
+	case nftables.TableFamilyARP:
+		if cha.Hook == nil || (*cha.Hook != *ChainHookARPIn && *cha.Hook != *ChainHookARPOut) {
+			return fmt.Errorf("arp base chain must have either ChainHookARPIn or ChainHookARPOut hook")
+		}
+		if cha.Type != nftables.ChainTypeFilter {
+			return fmt.Errorf("arp base chain must be of filter type")
+		}
+	}
+	if cha.Device != "" || len(cha.Devices) != 0 {
+		return fmt.Errorf("device can only be specified for a base chain of netdev table")
+	}
+
+	return nil
+}
+
+// devices returns the list of devices the base chain is bound to
+func (cha *ChainAttributes) devices() []string {
+	if cha.Device != "" {
+		return []string{cha.Device}
+	}
+	return cha.Devices
+}
+
 // ChainFuncs defines funcations to operate with chains
 type ChainFuncs interface {
 	Chain(name string) (RulesInterface, error)
@@ -72,6 +131,8 @@ type nfChains struct {
 type nfChain struct {
 	baseChain bool
 	chain     *nftables.Chain
+	// devChains carries per device base chains, when the chain is bound to more than one device.
+	devChains []*nftables.Chain
 	RulesInterface
 }
 
@@ -112,16 +173,34 @@ func isEqualChain(ch *nfChain, attributes *ChainAttributes) bool {
 	}
 	// Attributes must match
 	if attributes != nil {
-		if attributes.Hook != ch.chain.Hooknum ||
-			attributes.Type != ch.chain.Type ||
-			attributes.Priority != ch.chain.Priority {
+		c := ch.chain
+		if len(ch.devChains) != 0 {
+			c = ch.devChains[0]
+		}
+		// Hook and priority of chains synced from the kernel do not point to nftables' variables, values are compared
+		if !isEqualHook(attributes.Hook, c.Hooknum) ||
+			attributes.Type != c.Type ||
+			!isEqualPriority(attributes.Priority, c.Priority) {
+			return false
+		}
+		devs := attributes.devices()
+		if len(ch.devChains) != 0 {
+			if len(devs) != len(ch.devChains) {
+				return false
+			}
+			for i, dc := range ch.devChains {
+				if dc.Device != devs[i] {
+					return false
+				}
+			}
+		} else if len(devs) > 1 || (len(devs) == 1 && devs[0] != c.Device) {
 			return false
 		}
 		if attributes.Policy != nil {
-			if ch.chain.Policy == nil {
+			if c.Policy == nil {
 				return false
 			}
-			if nftables.ChainPolicy(*attributes.Policy) != *ch.chain.Policy {
+			if nftables.ChainPolicy(*attributes.Policy) != *c.Policy {
 				return false
 			}
 		}
@@ -130,6 +209,22 @@ func isEqualChain(ch *nfChain, attributes *ChainAttributes) bool {
 	return true
 }
 
+func isEqualHook(h1, h2 *nftables.ChainHook) bool {
+	if h1 == nil || h2 == nil {
+		return h1 == h2
+	}
+
+	return *h1 == *h2
+}
+
+func isEqualPriority(p1, p2 *nftables.ChainPriority) bool {
+	if p1 == nil || p2 == nil {
+		return p1 == p2
+	}
+
+	return *p1 == *p2
+}
+
 func (nfc *nfChains) create(name string, attributes *ChainAttributes) error {
 	if ch, ok := nfc.chains[name]; ok {
 		if isEqualChain(ch, attributes) {
@@ -137,6 +232,9 @@ func (nfc *nfChains) create(name string, attributes *ChainAttributes) error {
 		}
 		return fmt.Errorf("nftableslib: chain %s already exist in table %s", name, nfc.table.Name)
 	}
+	if nfc.devChainOwner(name) != nil {
+		return fmt.Errorf("nftableslib: chain %s already exist in table %s as a device chain", name, nfc.table.Name)
+	}
 
 	var baseChain bool
 	var c *nftables.Chain
@@ -144,11 +242,53 @@ func (nfc *nfChains) create(name string, attributes *ChainAttributes) error {
 		if err := attributes.Validate(); err != nil {
 			return err
 		}
+		if err := attributes.validateFamily(nfc.table.Family); err != nil {
+			return err
+		}
 		baseChain = true
 		policy := nftables.ChainPolicyAccept
 		if attributes.Policy != nil {
 			policy = nftables.ChainPolicy(*attributes.Policy)
 		}
+		if devs := attributes.devices(); len(devs) > 1 {
+			for _, dev := range devs {
+				if _, ok := nfc.chains[name+"-"+dev]; ok || nfc.devChainOwner(name+"-"+dev) != nil {
+					return fmt.Errorf("nftableslib: chain %s of device %s already exist in table %s", name+"-"+dev, dev, nfc.table.Name)
+				}
+			}
+			// A base chain can be bound only to a single device, the rules are carried by a regular chain
+			// and each device gets its own base chain jumping to it.
+			c = nfc.conn.AddChain(&nftables.Chain{
+				Name:  name,
+				Table: nfc.table,
+			})
+			devChains := make([]*nftables.Chain, 0, len(devs))
+			for _, dev := range devs {
+				dc := nfc.conn.AddChain(&nftables.Chain{
+					Name:     name + "-" + dev,
+					Hooknum:  attributes.Hook,
+					Priority: attributes.Priority,
+					Table:    nfc.table,
+					Type:     attributes.Type,
+					Policy:   &policy,
+					Device:   dev,
+				})
+				nfc.conn.AddRule(&nftables.Rule{
+					Table:    nfc.table,
+					Chain:    dc,
+					Exprs:    []expr.Any{&expr.Verdict{Kind: expr.VerdictJump, Chain: name}},
+					UserData: devChainUserData(dev),
+				})
+				devChains = append(devChains, dc)
+			}
+			nfc.chains[name] = &nfChain{
+				chain:          c,
+				baseChain:      baseChain,
+				devChains:      devChains,
+				RulesInterface: newRules(nfc.conn, nfc.table, c),
+			}
+			return nil
+		}
 		c = nfc.conn.AddChain(&nftables.Chain{
 			Name:     name,
 			Hooknum:  attributes.Hook,
@@ -156,7 +296,11 @@ func (nfc *nfChains) create(name string, attributes *ChainAttributes) error {
 			Table:    nfc.table,
 			Type:     attributes.Type,
 			Policy:   &policy,
+			Device:   attributes.Device,
 		})
+		if len(attributes.Devices) == 1 {
+			c.Device = attributes.Devices[0]
+		}
 	} else {
 		baseChain = false
 		c = nfc.conn.AddChain(&nftables.Chain{
@@ -198,7 +342,7 @@ func (nfc *nfChains) Delete(name string) error {
 	nfc.Lock()
 	defer nfc.Unlock()
 	if ch, ok := nfc.chains[name]; ok {
-		nfc.conn.DelChain(ch.chain)
+		nfc.delChain(ch)
 		delete(nfc.chains, name)
 	} else {
 		return fmt.Errorf("chain %s does not exists", name)
@@ -221,7 +365,7 @@ func (nfc *nfChains) DeleteImm(name string) error {
 	defer ticker.Stop()
 	for {
 		// Flush notifies netlink to proceed with removing of a chain
-		nfc.conn.DelChain(ch.chain)
+		nfc.delChain(ch)
 		if err = nfc.conn.Flush(); err == nil {
 			delete(nfc.chains, name)
 			return nil
@@ -239,28 +383,132 @@ func (nfc *nfChains) DeleteImm(name string) error {
 	}
 }
 
+// delChain removes the chain along with per device base chains referring to it.
+func (nfc *nfChains) delChain(ch *nfChain) {
+	for _, dc := range ch.devChains {
+		nfc.conn.DelChain(dc)
+	}
+	nfc.conn.DelChain(ch.chain)
+}
+
+// devChainOwner returns the chain the per device base chain with the specified name belongs to.
+func (nfc *nfChains) devChainOwner(name string) *nfChain {
+	for _, ch := range nfc.chains {
+		for _, dc := range ch.devChains {
+			if dc.Name == name {
+				return ch
+			}
+		}
+	}
+
+	return nil
+}
+
+// devChainUserData returns user data of the rule of per device base chain, it marks the chain as
+// created by the library and carries the device the chain is bound to.
+func devChainUserData(dev string) []byte {
+	// Device TLV:
+	//      [0] - TLV type , must be 0x3
+	//      [1] - Value length
+	//      [2:] - Device name
+	ud := make([]byte, len(dev)+2)
+	ud[0] = 0x3
+	ud[1] = uint8(len(dev))
+	copy(ud[2:], dev)
+
+	return ud
+}
+
+// devFromUserData returns the device stored in user data of the rule of per device base chain
+func devFromUserData(ud []byte) (string, error) {
+	if len(ud) < 3 || ud[0] != 0x3 || int(ud[1]) != len(ud)-2 {
+		return "", fmt.Errorf("did not find Device TLV in user data")
+	}
+
+	return string(ud[2:]), nil
+}
+
+// devChainTarget returns the name of the regular chain the per device base chain passes packets to and
+// the device the chain is bound to, an empty string is returned when the chain is not a per device base chain.
+func (nfc *nfChains) devChainTarget(chain *nftables.Chain, chains map[string]*nftables.Chain) (string, string, error) {
+	if nfc.table.Family != nftables.TableFamilyNetdev || chain.Type == "" {
+		return "", "", nil
+	}
+	rules, err := nfc.conn.GetRule(nfc.table, chain)
+	if err != nil {
+		return "", "", err
+	}
+	// Per device base chain carries a single rule jumping to the chain, the rule is marked by Device TLV
+	if len(rules) != 1 || len(rules[0].Exprs) != 1 {
+		return "", "", nil
+	}
+	dev, err := devFromUserData(rules[0].UserData)
+	if err != nil {
+		return "", "", nil
+	}
+	v, ok := rules[0].Exprs[0].(*expr.Verdict)
+	if !ok || v.Kind != expr.VerdictJump {
+		return "", "", nil
+	}
+	if target, ok := chains[v.Chain]; !ok || target.Type != "" {
+		return "", "", nil
+	}
+
+	return v.Chain, dev, nil
+}
+
 func (nfc *nfChains) Sync() error {
 	chains, err := nfc.conn.ListChains()
 	if err != nil {
 		return err
 	}
+	var tableChains []*nftables.Chain
+	names := make(map[string]*nftables.Chain)
 	for _, chain := range chains {
 		if chain.Table.Name == nfc.table.Name && chain.Table.Family == nfc.table.Family {
-			if _, ok := nfc.chains[chain.Name]; !ok {
-				baseChain := false
-				if chain.Type != "" && chain.Hooknum != nftables.ChainHookPrerouting { // unix.NF_INET_PRE_ROUTING = 0
-					baseChain = true
-				}
-				nfc.Lock()
-				nfc.chains[chain.Name] = &nfChain{
-					chain:          chain,
-					baseChain:      baseChain,
-					RulesInterface: newRules(nfc.conn, nfc.table, chain),
-				}
-				nfc.Unlock()
-				if err := nfc.chains[chain.Name].Rules().Sync(); err != nil {
-					return err
-				}
+			tableChains = append(tableChains, chain)
+			names[chain.Name] = chain
+		}
+	}
+	// Collecting per device base chains created by the library, they are carried by the chain they jump to
+	// and are not exposed as separate chains.
+	devChains := make(map[string][]*nftables.Chain)
+	devChain := make(map[string]bool)
+	for _, chain := range tableChains {
+		target, dev, err := nfc.devChainTarget(chain, names)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			continue
+		}
+		// The device is not returned by the kernel when chains are listed, it is recovered from the rule's user data.
+		chain.Device = dev
+		devChains[target] = append(devChains[target], chain)
+		devChain[chain.Name] = true
+	}
+	for _, chain := range tableChains {
+		if devChain[chain.Name] {
+			continue
+		}
+		if _, ok := nfc.chains[chain.Name]; !ok {
+			baseChain := false
+			if chain.Type != "" && chain.Hooknum != nftables.ChainHookPrerouting { // unix.NF_INET_PRE_ROUTING = 0
+				baseChain = true
+			}
+			if len(devChains[chain.Name]) != 0 {
+				baseChain = true
+			}
+			nfc.Lock()
+			nfc.chains[chain.Name] = &nfChain{
+				chain:          chain,
+				baseChain:      baseChain,
+				devChains:      devChains[chain.Name],
+				RulesInterface: newRules(nfc.conn, nfc.table, chain),
+			}
+			nfc.Unlock()
+			if err := nfc.chains[chain.Name].Rules().Sync(); err != nil {
+				return err
 			}
 		}
 	}
@@ -326,6 +574,10 @@ func (nfc *nfChains) Get() ([]string, error) {
 	var chainNames []string
 	for _, chain := range chains {
 		if nfc.table.Name == chain.Table.Name && nfc.table.Family == chain.Table.Family {
+			if nfc.devChainOwner(chain.Name) != nil {
+				// Per device base chains are not exposed as separate chains
+				continue
+			}
 			if _, ok := nfc.chains[chain.Name]; !ok {
 				// Found chain which is not in the store
 				// triggering Sync() to add it
@@ -1,9 +1,11 @@
 package nftableslib
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
 )
 
 func TestChains(t *testing.T) {
@@ -63,3 +65,109 @@ func TestChains(t *testing.T) {
 		}
 	}
 }
+
+func TestSyncDeviceChains(t *testing.T) {
+	tbl := &nftables.Table{Name: "filter-netdev", Family: nftables.TableFamilyNetdev}
+	policy := nftables.ChainPolicyAccept
+	devChain := func(name string) *nftables.Chain {
+		return &nftables.Chain{
+			Name:     name,
+			Table:    tbl,
+			Type:     nftables.ChainTypeFilter,
+			Hooknum:  nftables.ChainHookIngress,
+			Priority: nftables.ChainPriorityFilter,
+			Policy:   &policy,
+		}
+	}
+	jump := func(chain string, ud []byte) []*nftables.Rule {
+		return []*nftables.Rule{{Exprs: []expr.Any{&expr.Verdict{Kind: expr.VerdictJump, Chain: chain}}, UserData: ud}}
+	}
+	conn := &chainsConn{
+		chains: []*nftables.Chain{
+			{Name: "ingress", Table: tbl},
+			devChain("ingress-eth0"),
+			devChain("ingress-eth1"),
+			devChain("ingress-eth2"),
+			{Name: "single", Table: tbl},
+			devChain("single-eth1"),
+			{Name: "user", Table: tbl},
+			devChain("user-eth0"),
+		},
+		rules: map[string][]*nftables.Rule{
+			"ingress-eth0": jump("ingress", devChainUserData("eth0")),
+			"ingress-eth1": jump("ingress", devChainUserData("eth1")),
+			// User chains matching the name of device chain, but not marked by Device TLV
+			"ingress-eth2": jump("ingress", nil),
+			"user-eth0":    jump("user", []byte{0x0, 0x1, 0x0}),
+			// Device chain left after the removal of the other device of the chain
+			"single-eth1": jump("single", devChainUserData("eth1")),
+		},
+	}
+	nfc := newChains(conn, tbl).Chains()
+	if err := nfc.Sync(); err != nil {
+		t.Fatalf("failed to sync chains with error: %+v", err)
+	}
+	names, err := nfc.Get()
+	if err != nil {
+		t.Fatalf("failed to get chains with error: %+v", err)
+	}
+	expect := []string{"ingress", "ingress-eth2", "single", "user", "user-eth0"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Fatalf("chains %v do not match %v", names, expect)
+	}
+	if err := nfc.Create("single", &ChainAttributes{
+		Type:     nftables.ChainTypeFilter,
+		Hook:     nftables.ChainHookIngress,
+		Priority: nftables.ChainPriorityFilter,
+		Devices:  []string{"eth1"},
+	}); err != nil {
+		t.Fatalf("creating chain equal to synced chain with a single device chain failed with error: %+v", err)
+	}
+	attrs := &ChainAttributes{
+		Type:     nftables.ChainTypeFilter,
+		Hook:     nftables.ChainHookIngress,
+		Priority: nftables.ChainPriorityFilter,
+		Devices:  []string{"eth0", "eth1"},
+	}
+	if err := nfc.Create("ingress", attrs); err != nil {
+		t.Fatalf("creating chain equal to synced chain failed with error: %+v", err)
+	}
+	if err := nfc.Create("ingress-eth0", nil); err == nil {
+		t.Fatalf("creating chain colliding with device chain succeeded but supposed to fail")
+	}
+	if err := nfc.Create("user", &ChainAttributes{
+		Type:     nftables.ChainTypeFilter,
+		Hook:     nftables.ChainHookIngress,
+		Priority: nftables.ChainPriorityFilter,
+		Devices:  []string{"eth0", "eth1"},
+	}); err == nil {
+		t.Fatalf("creating chain with device chain colliding with existing chain succeeded but supposed to fail")
+	}
+	if err := nfc.Delete("ingress"); err != nil {
+		t.Fatalf("failed to delete chain with error: %+v", err)
+	}
+	expect = []string{"ingress-eth0", "ingress-eth1", "ingress"}
+	if !reflect.DeepEqual(conn.deleted, expect) {
+		t.Fatalf("deleted chains %v do not match %v", conn.deleted, expect)
+	}
+}
+
+// chainsConn returns a static list of chains and their rules and records deleted chains
+type chainsConn struct {
+	NetNS
+	chains  []*nftables.Chain
+	rules   map[string][]*nftables.Rule
+	deleted []string
+}
+
+func (c *chainsConn) ListChains() ([]*nftables.Chain, error) {
+	return c.chains, nil
+}
+
+func (c *chainsConn) GetRule(_ *nftables.Table, chain *nftables.Chain) ([]*nftables.Rule, error) {
+	return c.rules[chain.Name], nil
+}
+
+func (c *chainsConn) DelChain(chain *nftables.Chain) {
+	c.deleted = append(c.deleted, chain.Name)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/google/nftables"
 	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
 )
 
 // ConcatElement defines 1 element of Concatination rule
@@ -31,27 +32,25 @@ type Concat struct {
 	SetRef *SetRef
 }
 
-func getExprForConcat(l3proto nftables.TableFamily, concat *Concat) ([]expr.Any, error) {
-	var l3OffsetSrc, l3OffsetDst, l3AddrLen, l4ProtoOffset uint32
+func getExprForConcat(family nftables.TableFamily, concat *Concat) ([]expr.Any, error) {
 	l4OffsetSrc := uint32(0)
 	l4OffsetDst := uint32(2)
 	re := []expr.Any{}
-	switch l3proto {
-	case nftables.TableFamilyIPv4:
-		l3OffsetSrc = 12
-		l3OffsetDst = 16
-		l3AddrLen = 4
-		l4ProtoOffset = 9
-	case nftables.TableFamilyIPv6:
-		l3OffsetSrc = 8
-		l3OffsetDst = 24
-		l3AddrLen = 16
-		l4ProtoOffset = 6
-	default:
-		return nil, fmt.Errorf("unsupported table family %d", l3proto)
+	l3proto, err := getConcatL3Family(family, concat)
+	if err != nil {
+		return nil, err
+	}
+	var l3OffsetSrc, l3OffsetDst, l3AddrLen, l4ProtoOffset uint32
+	if !isMultiFamily(l3proto) {
+		if l3OffsetSrc, l3OffsetDst, l3AddrLen, l4ProtoOffset, err = l3Offsets(l3proto); err != nil {
+			return nil, err
+		}
 	}
-	register := uint32(1)
+	re = append(re, getExprForL3Guard(family, l3proto)...)
+	// Elements are loaded into consecutive 32-bit registers, index counts 32-bit registers taken by previous elements.
+	index := uint32(0)
 	for _, e := range concat.Elements {
+		register := concatRegister(index)
 		switch e.EType {
 		case nftables.TypeIPAddr:
 			// [ payload load length of address in bytes @ network header + l3OffsetSrc or l3OffsetDst => reg 1 ]
@@ -81,10 +80,25 @@ func getExprForConcat(l3proto nftables.TableFamily, concat *Concat) ([]expr.Any,
 				Offset:       offset,
 				Len:          l3AddrLen,
 			})
-			// Since IPv6 takes 16 bytes, need to increment register counter by 3.
-			register += 3
 		case nftables.TypeEtherAddr:
+			// [ payload load 6b @ link header + l2OffsetSrc or l2OffsetDst => reg 1 ]
+			offset := uint32(l2OffsetDst)
+			if e.ESource {
+				offset = l2OffsetSrc
+			}
+			re = append(re, &expr.Payload{
+				DestRegister: register,
+				Base:         expr.PayloadBaseLLHeader,
+				Offset:       offset,
+				Len:          6,
+			})
 		case nftables.TypeInetProto:
+			if isMultiFamily(l3proto) {
+				// Address family is not known, using family independent L4 protocol
+				// [ meta load l4proto => reg 1 ]
+				re = append(re, &expr.Meta{Key: expr.MetaKeyL4PROTO, Register: register})
+				break
+			}
 			// [ payload load 1b @ network header + 9 => reg 1 ]
 			re = append(re, &expr.Payload{
 				DestRegister: register,
@@ -109,11 +123,8 @@ func getExprForConcat(l3proto nftables.TableFamily, concat *Concat) ([]expr.Any,
 		default:
 			return nil, fmt.Errorf("unsupported element type %+v", e.EType)
 		}
-		if register == 1 {
-			register = 9
-		} else {
-			register++
-		}
+		// IPv6 address takes 4 registers and MAC address takes 2
+		index += (e.EType.Bytes + 3) / 4
 	}
 	// If Concat refers to map, add lookup expression
 	if concat.SetRef != nil {
@@ -128,3 +139,43 @@ func getExprForConcat(l3proto nftables.TableFamily, concat *Concat) ([]expr.Any,
 
 	return re, nil
 }
+
+// concatRegister returns the register of the concatenation's element starting at 32-bit register index, the first
+// element is loaded into reg 1 which starts at NFT_REG32_00, the following ones into NFT_REG32_XX registers.
+func concatRegister(index uint32) uint32 {
+	if index == 0 {
+		return unix.NFT_REG_1
+	}
+
+	return unix.NFT_REG32_00 + index
+}
+
+// getConcatL3Family returns the address family used to build expressions of Concat elements, for inet, netdev
+// and bridge tables the family is derived from address elements, if Concat does not carry any, the table family is returned.
+func getConcatL3Family(family nftables.TableFamily, concat *Concat) (nftables.TableFamily, error) {
+	switch family {
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6:
+		return family, nil
+	case nftables.TableFamilyINet, nftables.TableFamilyNetdev, nftables.TableFamilyBridge:
+	default:
+		return 0, fmt.Errorf("unsupported table family %d", family)
+	}
+	l3proto := family
+	for _, e := range concat.Elements {
+		var f nftables.TableFamily
+		switch e.EType {
+		case nftables.TypeIPAddr:
+			f = nftables.TableFamilyIPv4
+		case nftables.TypeIP6Addr:
+			f = nftables.TableFamilyIPv6
+		default:
+			continue
+		}
+		if l3proto != family && l3proto != f {
+			return 0, fmt.Errorf("cannot mix ipv4 and ipv6 elements in the same concatenation")
+		}
+		l3proto = f
+	}
+
+	return l3proto, nil
+}
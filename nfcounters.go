@@ -0,0 +1,307 @@
+package nftableslib
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// RuleCounter defines values of the counter of a rule, ID is 0 for rules which were not programmed by the library.
+type RuleCounter struct {
+	Chain   string
+	Handle  uint64
+	ID      uint32
+	Counter *CounterValues
+}
+
+// RuleCounterFuncs defines functions reading counters of rules of a chain, RuleFuncs returned by RulesInterface
+// implement it, example rf.(RuleCounterFuncs).GetCounters(false).
+type RuleCounterFuncs interface {
+	GetCounter(id uint32, reset bool) (*CounterValues, error)
+	GetCounterByHandle(handle uint64, reset bool) (*CounterValues, error)
+	GetCounters(reset bool) ([]*RuleCounter, error)
+}
+
+// ChainCounterFuncs defines functions reading counters of rules of a table, ChainFuncs returned by ChainsInterface
+// implement it, example cf.(ChainCounterFuncs).GetCounters(false).
+type ChainCounterFuncs interface {
+	GetCounters(reset bool) ([]*RuleCounter, error)
+}
+
+// GetCounter returns the values of the counter of a rule specified by its id, when reset is true,
+// the counter is atomically zeroed and values before the reset are returned.
+func (nfr *nfRules) GetCounter(id uint32, reset bool) (*CounterValues, error) {
+	handle, err := nfr.GetRuleHandle(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return nfr.GetCounterByHandle(handle, reset)
+}
+
+// GetCounterByHandle returns the values of the counter of a rule specified by its handle, when reset is true,
+// the counter is atomically zeroed and values before the reset are returned.
+func (nfr *nfRules) GetCounterByHandle(handle uint64, reset bool) (*CounterValues, error) {
+	var counters []*RuleCounter
+	var err error
+	if reset {
+		counters, err = resetRuleCounters(nfr.conn, nfr.table, nfr.chain.Name, handle)
+	} else {
+		counters, err = getRuleCounters(nfr.conn, nfr.table, nfr.chain)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range counters {
+		if c.Handle == handle {
+			return c.Counter, nil
+		}
+	}
+
+	return nil, fmt.Errorf("rule with handle %d does not have counter", handle)
+}
+
+// GetCounters returns the values of counters of all rules of the chain, rules without counter are skipped.
+func (nfr *nfRules) GetCounters(reset bool) ([]*RuleCounter, error) {
+	if reset {
+		return resetRuleCounters(nfr.conn, nfr.table, nfr.chain.Name, 0)
+	}
+
+	return getRuleCounters(nfr.conn, nfr.table, nfr.chain)
+}
+
+// GetCounters returns the values of counters of all rules of the table, rules without counter are skipped.
+func (nfc *nfChains) GetCounters(reset bool) ([]*RuleCounter, error) {
+	if reset {
+		return resetRuleCounters(nfc.conn, nfc.table, "", 0)
+	}
+	chains, err := nfc.conn.ListChains()
+	if err != nil {
+		return nil, err
+	}
+	counters := []*RuleCounter{}
+	for _, chain := range chains {
+		if nfc.table.Name != chain.Table.Name || nfc.table.Family != chain.Table.Family {
+			continue
+		}
+		c, err := getRuleCounters(nfc.conn, nfc.table, chain)
+		if err != nil {
+			return nil, err
+		}
+		counters = append(counters, c...)
+	}
+
+	return counters, nil
+}
+
+func getRuleCounters(conn NetNS, t *nftables.Table, c *nftables.Chain) ([]*RuleCounter, error) {
+	rules, err := conn.GetRule(t, c)
+	if err != nil {
+		return nil, err
+	}
+	counters := []*RuleCounter{}
+	for _, rule := range rules {
+		for _, e := range rule.Exprs {
+			counter, ok := e.(*expr.Counter)
+			if !ok {
+				continue
+			}
+			id, _ := ruleIDFromUserData(rule.UserData)
+			counters = append(counters, &RuleCounter{
+				Chain:  c.Name,
+				Handle: rule.Handle,
+				ID:     id,
+				Counter: &CounterValues{
+					Packets: counter.Packets,
+					Bytes:   counter.Bytes,
+				},
+			})
+			break
+		}
+	}
+
+	return counters, nil
+}
+
+// resetRuleCounters reads and resets counters of rules of a table, if chain is specified, only rules of the chain
+// are reset, if handle is specified, only the rule with the handle is reset.
+func resetRuleCounters(conn NetNS, t *nftables.Table, chain string, handle uint64) ([]*RuleCounter, error) {
+	rc, err := rulesResetConn(conn)
+	if err != nil {
+		return nil, err
+	}
+	req, err := resetRulesRequest(t, chain, handle)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := rc.ResetRules(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset counters of table %s with error: %+v", t.Name, err)
+	}
+	counters := []*RuleCounter{}
+	for _, msg := range msgs {
+		counter, err := ruleCounterFromMsg(msg)
+		if err != nil {
+			return nil, err
+		}
+		if counter.Counter != nil {
+			counters = append(counters, counter)
+		}
+	}
+
+	return counters, nil
+}
+
+// resetRulesRequest builds NFT_MSG_GETRULE_RESET request, the request for a single rule is not a dump.
+func resetRulesRequest(t *nftables.Table, chain string, handle uint64) (netlink.Message, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.ByteOrder = binary.BigEndian
+	ae.String(unix.NFTA_RULE_TABLE, t.Name)
+	flags := netlink.Request | netlink.Dump
+	if chain != "" {
+		ae.String(unix.NFTA_RULE_CHAIN, chain)
+	}
+	if handle != 0 {
+		ae.Uint64(unix.NFTA_RULE_HANDLE, handle)
+		flags = netlink.Request
+	}
+	data, err := ae.Encode()
+	if err != nil {
+		return netlink.Message{}, err
+	}
+
+	return netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(unix.NFNL_SUBSYS_NFTABLES<<8 | unix.NFT_MSG_GETRULE_RESET),
+			Flags: flags,
+		},
+		// nfgenmsg carrying the family of the table, version and resource id
+		Data: append([]byte{byte(t.Family), unix.NFNETLINK_V0, 0, 0}, data...),
+	}, nil
+}
+
+// rulesResetConn returns the connection's interface resetting rules
+func rulesResetConn(conn NetNS) (RulesResetConn, error) {
+	switch c := conn.(type) {
+	case RulesResetConn:
+		return c, nil
+	case *nftables.Conn:
+		return &netnsResetConn{netns: c.NetNS}, nil
+	}
+
+	return nil, fmt.Errorf("reset of counters is not supported by the connection")
+}
+
+// netnsResetConn resets rules over a netlink connection to the network namespace
+type netnsResetConn struct {
+	netns int
+}
+
+func (c *netnsResetConn) ResetRules(req netlink.Message) ([]netlink.Message, error) {
+	nl, err := netlink.Dial(unix.NETLINK_NETFILTER, &netlink.Config{NetNS: c.netns})
+	if err != nil {
+		return nil, err
+	}
+	defer nl.Close()
+
+	return nl.Execute(req)
+}
+
+// ruleCounterFromMsg decodes the chain, the handle, the id and the first counter of a rule message
+func ruleCounterFromMsg(msg netlink.Message) (*RuleCounter, error) {
+	if len(msg.Data) < 4 {
+		return nil, fmt.Errorf("invalid length %d of rule message", len(msg.Data))
+	}
+	ad, err := netlink.NewAttributeDecoder(msg.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	ad.ByteOrder = binary.BigEndian
+	rc := &RuleCounter{}
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_RULE_CHAIN:
+			rc.Chain = ad.String()
+		case unix.NFTA_RULE_HANDLE:
+			rc.Handle = ad.Uint64()
+		case unix.NFTA_RULE_USERDATA:
+			rc.ID, _ = ruleIDFromUserData(ad.Bytes())
+		case unix.NFTA_RULE_EXPRESSIONS:
+			ad.Nested(func(elems *netlink.AttributeDecoder) error {
+				for elems.Next() {
+					if rc.Counter != nil {
+						break
+					}
+					elems.Nested(func(e *netlink.AttributeDecoder) error {
+						e.ByteOrder = binary.BigEndian
+						name := ""
+						var data []byte
+						for e.Next() {
+							switch e.Type() {
+							case unix.NFTA_EXPR_NAME:
+								name = e.String()
+							case unix.NFTA_EXPR_DATA:
+								data = e.Bytes()
+							}
+						}
+						if name != "counter" {
+							return e.Err()
+						}
+						counter, err := counterValuesFromData(data)
+						if err != nil {
+							return err
+						}
+						rc.Counter = counter
+						return e.Err()
+					})
+				}
+				return elems.Err()
+			})
+		}
+	}
+	if err := ad.Err(); err != nil {
+		return nil, err
+	}
+
+	return rc, nil
+}
+
+func counterValuesFromData(data []byte) (*CounterValues, error) {
+	ad, err := netlink.NewAttributeDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+	ad.ByteOrder = binary.BigEndian
+	counter := &CounterValues{}
+	for ad.Next() {
+		switch ad.Type() {
+		case unix.NFTA_COUNTER_PACKETS:
+			counter.Packets = ad.Uint64()
+		case unix.NFTA_COUNTER_BYTES:
+			counter.Bytes = ad.Uint64()
+		}
+	}
+
+	return counter, ad.Err()
+}
+
+// ruleIDFromUserData returns Rule ID stored in last 4 bytes of rule's user data
+func ruleIDFromUserData(ud []byte) (uint32, error) {
+	// Rule ID TLV 4 bytes:
+	//      [0] - TLV type , must be 0x2
+	//      [1] - Value length, must be 2
+	//      [2:] - 2 bytes carrying Rule ID
+	if len(ud) < 4 || ud[len(ud)-4] != 0x2 || ud[len(ud)-3] != 0x2 {
+		return 0, fmt.Errorf("did not find Rule ID TLV in user data")
+	}
+	n := make([]byte, 4)
+	// Copy last 2 bytes of user data which carry rule id
+	copy(n[2:], ud[len(ud)-2:])
+
+	return binaryutil.BigEndian.Uint32(n), nil
+}
@@ -0,0 +1,228 @@
+package nftableslib
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+func TestRuleCounterFromMsg(t *testing.T) {
+	tests := []struct {
+		name   string
+		exprs  []string
+		id     []byte
+		expect *RuleCounter
+	}{
+		{
+			name:  "Rule with counter",
+			exprs: []string{"counter", "immediate"},
+			id:    []byte{0x2, 0x2, 0x0, 0xa},
+			expect: &RuleCounter{
+				Chain:   "chain-1",
+				Handle:  5,
+				ID:      10,
+				Counter: &CounterValues{Packets: 3, Bytes: 300},
+			},
+		},
+		{
+			name:  "Rule with counter after match",
+			exprs: []string{"meta", "cmp", "counter"},
+			expect: &RuleCounter{
+				Chain:   "chain-1",
+				Handle:  5,
+				Counter: &CounterValues{Packets: 3, Bytes: 300},
+			},
+		},
+		{
+			name:  "Rule without counter",
+			exprs: []string{"immediate"},
+			id:    []byte{0x2, 0x2, 0x0, 0xa},
+			expect: &RuleCounter{
+				Chain:  "chain-1",
+				Handle: 5,
+				ID:     10,
+			},
+		},
+	}
+	for _, tt := range tests {
+		rc, err := ruleCounterFromMsg(ruleMsg(t, tt.exprs, tt.id))
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(rc, tt.expect) {
+			t.Errorf("Test \"%s\" failed, decoded counter %+v does not match %+v", tt.name, rc, tt.expect)
+		}
+	}
+}
+
+// ruleMsg builds a rule message as it is sent by the kernel, counter expressions carry 3 packets and 300 bytes
+func ruleMsg(t *testing.T, exprs []string, id []byte) netlink.Message {
+	ae := netlink.NewAttributeEncoder()
+	ae.ByteOrder = binary.BigEndian
+	ae.String(unix.NFTA_RULE_TABLE, "table-1")
+	ae.String(unix.NFTA_RULE_CHAIN, "chain-1")
+	ae.Uint64(unix.NFTA_RULE_HANDLE, 5)
+	ae.Nested(unix.NLA_F_NESTED|unix.NFTA_RULE_EXPRESSIONS, func(nae *netlink.AttributeEncoder) error {
+		for _, name := range exprs {
+			nae.Nested(unix.NLA_F_NESTED|unix.NFTA_LIST_ELEM, func(e *netlink.AttributeEncoder) error {
+				e.ByteOrder = binary.BigEndian
+				e.String(unix.NFTA_EXPR_NAME, name)
+				if name == "counter" {
+					e.Nested(unix.NLA_F_NESTED|unix.NFTA_EXPR_DATA, func(d *netlink.AttributeEncoder) error {
+						d.ByteOrder = binary.BigEndian
+						d.Uint64(unix.NFTA_COUNTER_BYTES, 300)
+						d.Uint64(unix.NFTA_COUNTER_PACKETS, 3)
+						return nil
+					})
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	if id != nil {
+		ae.Bytes(unix.NFTA_RULE_USERDATA, id)
+	}
+	data, err := ae.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode rule message with error: %+v", err)
+	}
+
+	return netlink.Message{Data: append([]byte{unix.NFPROTO_IPV4, unix.NFNETLINK_V0, 0, 0}, data...)}
+}
+
+func TestGetRuleCounters(t *testing.T) {
+	conn := &countersConn{
+		rules: []*nftables.Rule{
+			{
+				Handle:   3,
+				Exprs:    []expr.Any{&expr.Counter{Packets: 1, Bytes: 100}, &expr.Verdict{Kind: expr.VerdictAccept}},
+				UserData: []byte{0x2, 0x2, 0x0, 0xb},
+			},
+			{
+				Handle: 4,
+				Exprs:  []expr.Any{&expr.Verdict{Kind: expr.VerdictDrop}},
+			},
+		},
+	}
+	nfr := newRules(conn, &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv4}, &nftables.Chain{Name: "chain-1"})
+	rc, ok := nfr.Rules().(RuleCounterFuncs)
+	if !ok {
+		t.Fatalf("rules do not implement RuleCounterFuncs")
+	}
+	counters, err := rc.GetCounters(false)
+	if err != nil {
+		t.Fatalf("failed to get counters with error: %+v", err)
+	}
+	expect := []*RuleCounter{{Chain: "chain-1", Handle: 3, ID: 11, Counter: &CounterValues{Packets: 1, Bytes: 100}}}
+	if !reflect.DeepEqual(counters, expect) {
+		t.Fatalf("counters %+v do not match %+v", counters, expect)
+	}
+	counter, err := rc.GetCounter(11, false)
+	if err != nil {
+		t.Fatalf("failed to get counter of rule with error: %+v", err)
+	}
+	if !reflect.DeepEqual(counter, expect[0].Counter) {
+		t.Fatalf("counter %+v does not match %+v", counter, expect[0].Counter)
+	}
+	if _, err := rc.GetCounterByHandle(4, false); err == nil {
+		t.Fatalf("getting counter of rule without counter succeeded but supposed to fail")
+	}
+	if _, err := rc.GetCounters(true); err == nil {
+		t.Fatalf("reset of counters succeeded but supposed to fail")
+	}
+}
+
+// countersConn returns a static list of rules
+type countersConn struct {
+	NetNS
+	rules []*nftables.Rule
+}
+
+func (c *countersConn) GetRule(*nftables.Table, *nftables.Chain) ([]*nftables.Rule, error) {
+	return c.rules, nil
+}
+
+func TestResetRuleCounters(t *testing.T) {
+	tests := []struct {
+		name   string
+		chain  string
+		handle uint64
+		flags  netlink.HeaderFlags
+	}{
+		{
+			name:  "Table",
+			flags: netlink.Request | netlink.Dump,
+		},
+		{
+			name:  "Chain",
+			chain: "chain-1",
+			flags: netlink.Request | netlink.Dump,
+		},
+		{
+			name:   "Rule",
+			chain:  "chain-1",
+			handle: 5,
+			flags:  netlink.Request,
+		},
+	}
+	for _, tt := range tests {
+		conn := &resetConn{msgs: []netlink.Message{ruleMsg(t, []string{"counter"}, nil), ruleMsg(t, []string{"immediate"}, nil)}}
+		counters, err := resetRuleCounters(conn, &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv6}, tt.chain, tt.handle)
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(counters) != 1 {
+			t.Errorf("Test \"%s\" failed, %d counters returned but supposed to be 1", tt.name, len(counters))
+		}
+		req := conn.req
+		if req.Header.Type != netlink.HeaderType(unix.NFNL_SUBSYS_NFTABLES<<8|unix.NFT_MSG_GETRULE_RESET) {
+			t.Errorf("Test \"%s\" failed, message type is %#x but supposed to be GETRULE_RESET", tt.name, req.Header.Type)
+		}
+		if req.Header.Flags != tt.flags {
+			t.Errorf("Test \"%s\" failed, flags are %s but supposed to be %s", tt.name, req.Header.Flags, tt.flags)
+		}
+		if req.Data[0] != unix.NFPROTO_IPV6 {
+			t.Errorf("Test \"%s\" failed, family is %d but supposed to be %d", tt.name, req.Data[0], unix.NFPROTO_IPV6)
+		}
+		ad, err := netlink.NewAttributeDecoder(req.Data[4:])
+		if err != nil {
+			t.Fatalf("Test \"%s\" failed to decode request with error: %+v", tt.name, err)
+		}
+		ad.ByteOrder = binary.BigEndian
+		var table, chain string
+		var handle uint64
+		for ad.Next() {
+			switch ad.Type() {
+			case unix.NFTA_RULE_TABLE:
+				table = ad.String()
+			case unix.NFTA_RULE_CHAIN:
+				chain = ad.String()
+			case unix.NFTA_RULE_HANDLE:
+				handle = ad.Uint64()
+			}
+		}
+		if table != "table-1" || chain != tt.chain || handle != tt.handle {
+			t.Errorf("Test \"%s\" failed, request of table %q chain %q handle %d does not match", tt.name, table, chain, handle)
+		}
+	}
+}
+
+// resetConn records the reset request and replies with static messages
+type resetConn struct {
+	NetNS
+	req  netlink.Message
+	msgs []netlink.Message
+}
+
+func (c *resetConn) ResetRules(req netlink.Message) ([]netlink.Message, error) {
+	c.req = req
+	return c.msgs, nil
+}
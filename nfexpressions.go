@@ -2,6 +2,7 @@ package nftableslib
 
 import (
 	"fmt"
+	"strings"
 
 	"golang.org/x/sys/unix"
 
@@ -17,24 +18,52 @@ func ifname(n string) []byte {
 	return b
 }
 
-func inputIntfByName(intf string) []expr.Any {
-	return []expr.Any{
-		&expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1},
-		&expr.Cmp{
-			Op:       expr.CmpOpEq,
-			Register: 1,
-			Data:     ifname(intf),
-		},
-	}
+// getCmpOp returns cmp operation corresponding to the relational operator
+func getCmpOp(op Operator) expr.CmpOp {
+	switch op {
+	case NEQ:
+		return expr.CmpOpNeq
+	case LT:
+		return expr.CmpOpLt
+	case GT:
+		return expr.CmpOpGt
+	case LTE:
+		return expr.CmpOpLte
+	case GTE:
+		return expr.CmpOpGte
+	}
+
+	return expr.CmpOpEq
 }
 
-func outputIntfByName(intf string) []expr.Any {
+func inputIntfByName(intf string, op Operator) []expr.Any {
+	return getExprForIntfName(expr.MetaKeyIIFNAME, intf, op)
+}
+
+func outputIntfByName(intf string, op Operator) []expr.Any {
+	return getExprForIntfName(expr.MetaKeyOIFNAME, intf, op)
+}
+
+// getExprForIntfName returns expression to match interface name, if the name ends with '*',
+// only the prefix of the name is compared.
+func getExprForIntfName(key expr.MetaKey, intf string, op Operator) []expr.Any {
+	cmpOp := expr.CmpOpEq
+	if op == NEQ {
+		cmpOp = expr.CmpOpNeq
+	}
+	data := ifname(intf)
+	if strings.HasSuffix(intf, "*") {
+		// [ cmp eq reg 1 0x00687465 ]
+		data = []byte(strings.TrimSuffix(intf, "*"))
+	}
+	// [ meta load iifname => reg 1 ]
+	// [ cmp eq reg 1 0x30687465 0x00000000 0x00000000 0x00000000 ]
 	return []expr.Any{
-		&expr.Meta{Key: expr.MetaKeyOIFNAME, Register: 1},
+		&expr.Meta{Key: key, Register: 1},
 		&expr.Cmp{
-			Op:       expr.CmpOpEq,
+			Op:       cmpOp,
 			Register: 1,
-			Data:     ifname(intf),
+			Data:     data,
 		},
 	}
 }
@@ -46,6 +75,40 @@ func getExprForCounter() []expr.Any {
 	}
 }
 
+func getExprForLimit(l *Limit) ([]expr.Any, error) {
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	limitType := expr.LimitTypePkts
+	if l.Bytes {
+		limitType = expr.LimitTypePktBytes
+	}
+	// [ limit rate 10/second burst 5 type packets flags 0x0 ]
+	return []expr.Any{
+		&expr.Limit{
+			Type:  limitType,
+			Rate:  l.Rate,
+			Unit:  expr.LimitTime(l.Unit),
+			Burst: l.Burst,
+			Over:  l.Over,
+		},
+	}, nil
+}
+
+func getExprForQuota(q *Quota) ([]expr.Any, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	// [ quota bytes 1048576 consumed 0 flags 0 ]
+	return []expr.Any{
+		&expr.Quota{
+			Bytes:    q.Bytes,
+			Consumed: q.Consumed,
+			Over:     q.Over,
+		},
+	}, nil
+}
+
 // getExprForSingleIP returns expression to match a single IPv4 or IPv6 address
 func getExprForSingleIP(l3proto nftables.TableFamily, offset uint32, addr *IPAddr, op Operator) ([]expr.Any, error) {
 	if addr == nil {
@@ -72,6 +135,10 @@ func getExprForSingleIP(l3proto nftables.TableFamily, offset uint32, addr *IPAdd
 	if len(baddr) == 0 {
 		return nil, fmt.Errorf("invalid ip %s", addr.IP.String())
 	}
+	// Addresses with a prefix can only be equal or not equal to the network address
+	if err := validateRelOp(op, !addr.CIDR); err != nil {
+		return nil, err
+	}
 	xor = make([]byte, addrLen)
 	re = append(re, &expr.Bitwise{
 		SourceRegister: 1,
@@ -80,12 +147,8 @@ func getExprForSingleIP(l3proto nftables.TableFamily, offset uint32, addr *IPAdd
 		Mask:           buildMask(addrLen, *addr.Mask),
 		Xor:            xor,
 	})
-	cmpOp := expr.CmpOpEq
-	if op == NEQ {
-		cmpOp = expr.CmpOpNeq
-	}
 	re = append(re, &expr.Cmp{
-		Op:       cmpOp,
+		Op:       getCmpOp(op),
 		Register: 1,
 		Data:     baddr,
 	})
@@ -98,6 +161,9 @@ func getExprForListIP(l3proto nftables.TableFamily, set *nftables.Set, offset ui
 	if set == nil {
 		return nil, fmt.Errorf("set *nftables.Set cannot be nil")
 	}
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 
 	addrLen := 4
@@ -129,6 +195,9 @@ func getExprForRangeIP(l3proto nftables.TableFamily, offset uint32, rng [2]*IPAd
 	if rng[0] == nil || rng[1] == nil {
 		return nil, fmt.Errorf("ip address in the range cannot be nil")
 	}
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 
 	addrLen := 4
@@ -205,6 +274,10 @@ func getExprForListPort(l4proto uint8, offset uint32, port []*uint16, op Operato
 	if l4proto == 0 {
 		return nil, fmt.Errorf("l4 protocol is 0")
 	}
+	// A single port can be compared by any relational operation, a list of ports is matched by a lookup
+	if err := validateRelOp(op, len(port) == 1); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 	re = append(re, &expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1})
 	re = append(re, &expr.Cmp{
@@ -235,12 +308,8 @@ func getExprForListPort(l4proto uint8, offset uint32, port []*uint16, op Operato
 		})
 	} else {
 		// Case for a single port list
-		cmpOp := expr.CmpOpEq
-		if excl {
-			cmpOp = expr.CmpOpNeq
-		}
 		re = append(re, &expr.Cmp{
-			Op:       cmpOp,
+			Op:       getCmpOp(op),
 			Register: 1,
 			Data:     binaryutil.BigEndian.PutUint16(*port[0]),
 		})
@@ -249,19 +318,6 @@ func getExprForListPort(l4proto uint8, offset uint32, port []*uint16, op Operato
 	return re, nil
 }
 
-func getExprForTProxyRedirect(port uint16, family nftables.TableFamily) []expr.Any {
-	re := []expr.Any{}
-	re = append(re, &expr.Immediate{Register: 1, Data: binaryutil.BigEndian.PutUint16(port)})
-	re = append(re,
-		&expr.TProxy{
-			Family:      byte(family),
-			TableFamily: byte(family),
-			RegPort:     1,
-		})
-
-	return re
-}
-
 func getExprForRedirect(port uint16, family nftables.TableFamily) []expr.Any {
 	re := []expr.Any{}
 	re = append(re, &expr.Immediate{Register: 1, Data: binaryutil.BigEndian.PutUint16(port)})
@@ -290,6 +346,9 @@ func getExprForRangePort(l4proto uint8, offset uint32, port [2]*uint16, op Opera
 	if l4proto == 0 {
 		return nil, fmt.Errorf("l4 protocol is 0")
 	}
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 	re = append(re, &expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1})
 	re = append(re, &expr.Cmp{
@@ -304,11 +363,12 @@ func getExprForRangePort(l4proto uint8, offset uint32, port [2]*uint16, op Opera
 		Len:          2,      // 2 bytes for port
 	})
 	if op == NEQ {
+		// [ range neq reg 1 0x00003930 0x000031d4 ]
 		re = append(re, &expr.Range{
 			Op:       expr.CmpOpNeq,
 			Register: 1,
-			FromData: binaryutil.NativeEndian.PutUint16(*port[0]),
-			ToData:   binaryutil.NativeEndian.PutUint16(*port[1]),
+			FromData: binaryutil.BigEndian.PutUint16(*port[0]),
+			ToData:   binaryutil.BigEndian.PutUint16(*port[1]),
 		})
 		return re, nil
 	}
@@ -327,6 +387,9 @@ func getExprForRangePort(l4proto uint8, offset uint32, port [2]*uint16, op Opera
 }
 
 func getExprForIPVersion(version byte, op Operator) ([]expr.Any, error) {
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 	re = append(re, &expr.Payload{
 		DestRegister: 1,
@@ -334,10 +397,6 @@ func getExprForIPVersion(version byte, op Operator) ([]expr.Any, error) {
 		Offset:       0, // Offset for a version of IP
 		Len:          1, // 1 byte for IP version
 	})
-	if op != EQ {
-		// TODO sbezverk
-		return re, nil
-	}
 	re = append(re, &expr.Bitwise{
 		SourceRegister: 1,
 		DestRegister:   1,
@@ -347,7 +406,7 @@ func getExprForIPVersion(version byte, op Operator) ([]expr.Any, error) {
 	})
 
 	re = append(re, &expr.Cmp{
-		Op:       expr.CmpOpEq,
+		Op:       getCmpOp(op),
 		Register: 1,
 		Data:     []byte{(version << 4)},
 	})
@@ -356,8 +415,12 @@ func getExprForIPVersion(version byte, op Operator) ([]expr.Any, error) {
 }
 
 func getExprForProtocol(l3proto nftables.TableFamily, proto uint32, op Operator) ([]expr.Any, error) {
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
-	if l3proto == nftables.TableFamilyIPv4 {
+	switch l3proto {
+	case nftables.TableFamilyIPv4:
 		// IPv4
 		// [ payload load 1b @ network header + 9 => reg 1 ]
 		re = append(re, &expr.Payload{
@@ -366,7 +429,7 @@ func getExprForProtocol(l3proto nftables.TableFamily, proto uint32, op Operator)
 			Offset:       9, // Offset for a L4 protocol
 			Len:          1, // 1 byte for L4 protocol
 		})
-	} else {
+	case nftables.TableFamilyIPv6:
 		// IPv6
 		//	[ payload load 1b @ network header + 6 => reg 1 ]
 		re = append(re, &expr.Payload{
@@ -375,16 +438,18 @@ func getExprForProtocol(l3proto nftables.TableFamily, proto uint32, op Operator)
 			Offset:       6, // Offset for a L4 protocol
 			Len:          1, // 1 byte for L4 protocol
 		})
+	case nftables.TableFamilyINet, nftables.TableFamilyNetdev, nftables.TableFamilyBridge:
+		// Address family is not known, using family independent L4 protocol
+		//	[ meta load l4proto => reg 1 ]
+		re = append(re, &expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1})
+	default:
+		return nil, fmt.Errorf("unsupported table family %d", l3proto)
 	}
 
-	if op != EQ {
-		// TODO sbezverk
-		return re, nil
-	}
 	// [ cmp eq reg 1 0x00000006 ]
 	protobyte := binaryutil.NativeEndian.PutUint32(proto)
 	re = append(re, &expr.Cmp{
-		Op:       expr.CmpOpEq,
+		Op:       getCmpOp(op),
 		Register: 1,
 		Data:     protobyte[0:1],
 	})
@@ -392,10 +457,57 @@ func getExprForProtocol(l3proto nftables.TableFamily, proto uint32, op Operator)
 	return re, nil
 }
 
-func getExprForMetaMark(mark *MetaMark) []expr.Any {
-	if mark == nil {
+// getExprForNFProto returns expression to match netfilter protocol family of a packet,
+// it is used to guard address family specific expressions in inet table.
+func getExprForNFProto(l3proto nftables.TableFamily) []expr.Any {
+	// [ meta load nfproto => reg 1 ]
+	// [ cmp eq reg 1 0x00000002 ]
+	return []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     []byte{byte(l3proto)},
+		},
+	}
+}
+
+// getExprForMetaProtocol returns expression to match ethertype of a packet, it is used to guard
+// address family specific expressions in netdev and bridge tables, where nfproto does not reflect the packet's family.
+func getExprForMetaProtocol(l3proto nftables.TableFamily) []expr.Any {
+	ethType := uint16(unix.ETH_P_IP)
+	if l3proto == nftables.TableFamilyIPv6 {
+		ethType = unix.ETH_P_IPV6
+	}
+	// [ meta load protocol => reg 1 ]
+	// [ cmp eq reg 1 0x00000008 ]
+	return []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyPROTOCOL, Register: 1},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(ethType),
+		},
+	}
+}
+
+// getExprForL3Guard returns expression guarding address family specific expressions in tables
+// carrying packets of both ipv4 and ipv6 families.
+func getExprForL3Guard(family, l3proto nftables.TableFamily) []expr.Any {
+	if !isMultiFamily(family) || isMultiFamily(l3proto) {
 		return []expr.Any{}
 	}
+	if family == nftables.TableFamilyINet {
+		return getExprForNFProto(l3proto)
+	}
+
+	return getExprForMetaProtocol(l3proto)
+}
+
+func getExprForMetaMark(mark *MetaMark) ([]expr.Any, error) {
+	if mark == nil {
+		return []expr.Any{}, nil
+	}
 
 	// Apply mask to mark if needed
 	maskedMark := mark.Value
@@ -438,32 +550,65 @@ func getExprForMetaMark(mark *MetaMark) []expr.Any {
 				Xor:            []byte{0x0, 0x0, 0x0, 0x0},
 			})
 		}
+		if err := validateRelOp(mark.RelOp, true); err != nil {
+			return nil, err
+		}
 		// [ cmp eq reg 1 0x0000dead ]
-		re = append(re, &expr.Cmp{
-			Op:       expr.CmpOpEq,
-			Register: 1,
-			Data:     binaryutil.NativeEndian.PutUint32(maskedMark),
-		})
+		re = append(re, getExprForHostOrderCmp(mark.RelOp, binaryutil.NativeEndian.PutUint32(maskedMark))...)
 	}
 
-	return re
+	return re, nil
 }
 
-func getExprForMetaExpr(meta []MetaExpr) []expr.Any {
+// getExprForHostOrderCmp returns expression to compare the value stored in the host byte order, example mark,
+// with the content of register 1. Since LT, GT, LTE and GTE compare bytes of the value, for these operations
+// both the register and the value are converted to the network byte order.
+func getExprForHostOrderCmp(op Operator, value []byte) []expr.Any {
+	var data []byte
+	switch {
+	case op == EQ || op == NEQ:
+	case len(value) == 2:
+		data = binaryutil.BigEndian.PutUint16(binaryutil.NativeEndian.Uint16(value))
+	case len(value) == 4:
+		data = binaryutil.BigEndian.PutUint32(binaryutil.NativeEndian.Uint32(value))
+	}
+	if data == nil {
+		return []expr.Any{&expr.Cmp{
+			Op:       getCmpOp(op),
+			Register: 1,
+			Data:     value,
+		}}
+	}
+	// [ byteorder reg 1 = hton(reg 1, 4, 4) ]
+	// [ cmp lt reg 1 0xadde0000 ]
+	return []expr.Any{
+		&expr.Byteorder{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Op:             expr.ByteorderHton,
+			Len:            uint32(len(value)),
+			Size:           uint32(len(value)),
+		},
+		&expr.Cmp{
+			Op:       getCmpOp(op),
+			Register: 1,
+			Data:     data,
+		},
+	}
+}
+
+func getExprForMetaExpr(meta []MetaExpr) ([]expr.Any, error) {
 	re := []expr.Any{}
 	for _, m := range meta {
-		op := expr.CmpOpEq
-		if m.RelOp == NEQ {
-			op = expr.CmpOpNeq
+		// Values of 4 bytes are carried in the host byte order and values of a single byte have no byte order,
+		// other values can only be equal or not equal.
+		if err := validateRelOp(m.RelOp, len(m.Value) == 1 || len(m.Value) == 4); err != nil {
+			return nil, err
 		}
 		re = append(re, &expr.Meta{Key: expr.MetaKey(m.Key), Register: 1})
-		re = append(re, &expr.Cmp{
-			Op:       op,
-			Register: 1,
-			Data:     m.Value,
-		})
+		re = append(re, getExprForHostOrderCmp(m.RelOp, m.Value)...)
 	}
-	return re
+	return re, nil
 }
 
 func getExprForMasq(masq *masquerade) []expr.Any {
@@ -517,19 +662,64 @@ func getExprForLog(log *Log) []expr.Any {
 	return re
 }
 
-func getExprForReject(r *reject) []expr.Any {
-	if r == nil {
+func getExprForQueue(q *queue) []expr.Any {
+	if q == nil {
 		return []expr.Any{}
 	}
 	re := []expr.Any{}
-	re = append(re, &expr.Reject{Type: r.rejectType, Code: r.rejectCode})
+	// [ queue num 0-3 bypass,fanout ]
+	re = append(re, &expr.Queue{Num: q.num, Total: q.total, Flag: q.flags})
 
 	return re
 }
 
-func getExprForFib(f *Fib) []expr.Any {
+func getExprForDup(family nftables.TableFamily, d *dup) ([]expr.Any, error) {
+	re := []expr.Any{}
+	switch family {
+	case nftables.TableFamilyNetdev:
+		if d.addr != nil || d.dev == 0 {
+			return nil, fmt.Errorf("dup in %s table requires device and no address", familyName(family))
+		}
+		// [ immediate reg 1 0x00000002 ]
+		// [ dup sreg_dev 1 ]
+		re = append(re, &expr.Immediate{Register: 1, Data: binaryutil.NativeEndian.PutUint32(d.dev)})
+		re = append(re, &expr.Dup{RegDev: 1, IsRegDevSet: true})
+		return re, nil
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6:
+	default:
+		return nil, fmt.Errorf("dup is not supported in %s table", familyName(family))
+	}
+	if d.addr == nil {
+		return nil, fmt.Errorf("dup in %s table requires address", familyName(family))
+	}
+	if d.addr.IsIPv6() != (family == nftables.TableFamilyIPv6) {
+		return nil, fmt.Errorf("address %s of dup does not match %s table", d.addr.IP.String(), familyName(family))
+	}
+	addr := d.addr.IP.To4()
+	if family == nftables.TableFamilyIPv6 {
+		addr = d.addr.IP.To16()
+	}
+	// [ immediate reg 1 0x0100000a ]
+	re = append(re, &expr.Immediate{Register: 1, Data: addr})
+	if d.dev == 0 {
+		// [ dup sreg_addr 1 ]
+		re = append(re, &expr.Dup{RegAddr: 1})
+		return re, nil
+	}
+	// [ immediate reg 2 0x00000002 ]
+	// [ dup sreg_addr 1 sreg_dev 2 ]
+	re = append(re, &expr.Immediate{Register: 2, Data: binaryutil.NativeEndian.PutUint32(d.dev)})
+	re = append(re, &expr.Dup{RegAddr: 1, RegDev: 2, IsRegDevSet: true})
+
+	return re, nil
+}
+
+func getExprForFib(f *Fib) ([]expr.Any, error) {
 	if f == nil {
-		return []expr.Any{}
+		return []expr.Any{}, nil
+	}
+	if err := validateRelOp(f.RelOp, false); err != nil {
+		return nil, err
 	}
 	// [ fib daddr type => reg 1 ]
 	// [ cmp eq reg 1 0x00000002 ]
@@ -546,10 +736,6 @@ func getExprForFib(f *Fib) []expr.Any {
 		FlagPRESENT:    f.FlagPRESENT,
 	})
 
-	op := expr.CmpOpEq
-	if f.RelOp == NEQ {
-		op = expr.CmpOpNeq
-	}
 	l := len(f.Data) / 4
 	if len(f.Data)%4 != 0 {
 		l++
@@ -557,48 +743,12 @@ func getExprForFib(f *Fib) []expr.Any {
 	data := make([]byte, l*4)
 	copy(data, f.Data)
 	re = append(re, &expr.Cmp{
-		Op:       op,
+		Op:       getCmpOp(f.RelOp),
 		Register: 1,
 		Data:     data,
 	})
 
-	return re
-}
-
-func getExprForConntracks(cts []*Conntrack) []expr.Any {
-	re := []expr.Any{}
-	for _, ct := range cts {
-		if ct == nil {
-			// Skipping nil pointers
-			continue
-		}
-		switch ct.Key {
-		// List of supported conntrack keys
-		case unix.NFT_CT_STATE:
-			//	[ ct load state => reg 1 ]
-			//	[ bitwise reg 1 = (reg=1 & 0x00000008 ) ^ 0x00000000 ]
-			//	[ cmp neq reg 1 0x00000000 ]
-			re = append(re, &expr.Ct{Key: unix.NFT_CT_STATE, Register: 1})
-			re = append(re, &expr.Bitwise{
-				SourceRegister: 1,
-				DestRegister:   1,
-				Len:            4,
-				Mask:           ct.Value,
-				Xor:            []byte{0x0, 0x0, 0x0, 0x0},
-			})
-			re = append(re, &expr.Cmp{
-				Op:       expr.CmpOpNeq,
-				Register: 1,
-				Data:     []byte{0x0, 0x0, 0x0, 0x0},
-			})
-		case unix.NFT_CT_DIRECTION:
-		case unix.NFT_CT_STATUS:
-		case unix.NFT_CT_LABELS:
-		case unix.NFT_CT_EVENTMASK:
-		}
-	}
-
-	return re
+	return re, nil
 }
 
 func getExprForPortSet(l4proto uint8, offset uint32, set *SetRef, op Operator) ([]expr.Any, error) {
@@ -608,6 +758,9 @@ func getExprForPortSet(l4proto uint8, offset uint32, set *SetRef, op Operator) (
 	if l4proto == 0 {
 		return nil, fmt.Errorf("l4 protocol is 0")
 	}
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 	re = append(re, &expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1})
 	re = append(re, &expr.Cmp{
@@ -646,6 +799,9 @@ func getExprForAddrSet(l3proto nftables.TableFamily, offset uint32, set *SetRef,
 	if set == nil {
 		return nil, fmt.Errorf("set *SetRef cannot be nil")
 	}
+	if err := validateRelOp(op, false); err != nil {
+		return nil, err
+	}
 	re := []expr.Any{}
 	addrLen := 4
 	if l3proto == nftables.TableFamilyIPv6 {
@@ -690,6 +846,22 @@ func getExprForNAT(l3proto nftables.TableFamily, nat *nat) ([]expr.Any, error) {
 
 	var regAddrMin, regAddrMax, regProtoMin, regProtoMax uint32
 	register := uint32(1)
+	// inet table requires nat's address family to match the family of translated addresses
+	if l3proto == nftables.TableFamilyINet && nat.address != nil {
+		var addr *IPAddr
+		switch {
+		case len(nat.address.List) != 0:
+			addr = nat.address.List[0]
+		case nat.address.Range[0] != nil:
+			addr = nat.address.Range[0]
+		}
+		if addr != nil {
+			l3proto = nftables.TableFamilyIPv4
+			if addr.IsIPv6() {
+				l3proto = nftables.TableFamilyIPv6
+			}
+		}
+	}
 	if nat.address != nil {
 		var addr1, addr2 []byte
 		// NAT does not support a list of addresses, it supports either a single address List[0]
@@ -804,21 +976,63 @@ func getExprForLoadbalance(nfr *nfRules, l *loadbalance) ([]expr.Any, error) {
 	if l.mode == unix.NFT_NG_INCREMENTAL {
 		mode = uint32(unix.NFT_NG_INCREMENTAL)
 	}
+	// Each chain gets the number of buckets equal to its weight, weights are reduced by their greatest common divisor
+	weights := l.weights
+	if weights == nil {
+		weights = make([]int, len(l.chains))
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	d := weights[0]
+	for _, w := range weights {
+		for w != 0 {
+			d, w = w, d%w
+		}
+	}
 	for ind, chain := range l.chains {
-		elements = append(elements, nftables.SetElement{
-			Key: binaryutil.NativeEndian.PutUint32(uint32(ind)),
-			VerdictData: &expr.Verdict{
-				Kind:  expr.VerdictKind(action),
-				Chain: chain,
-			},
+		for i := 0; i < weights[ind]/d; i++ {
+			elements = append(elements, nftables.SetElement{
+				Key: binaryutil.NativeEndian.PutUint32(uint32(len(elements))),
+				VerdictData: &expr.Verdict{
+					Kind:  expr.VerdictKind(action),
+					Chain: chain,
+				},
+			})
+		}
+	}
+	switch {
+	case l.hash != nil && l.hash.Symmetric:
+		// [ hash reg 1 = symhash() % mod 2 ]
+		exprs = append(exprs, &expr.Hash{
+			DestRegister: 1,
+			Modulus:      uint32(len(elements)),
+			Type:         expr.HashTypeSym,
+		})
+	case l.hash != nil:
+		e, err := getExprForConcat(nfr.table.Family, &Concat{Elements: l.hash.Fields})
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e...)
+		// [ hash reg 1 = jhash(reg 1, 8, 0x2a) % mod 2 ]
+		exprs = append(exprs, &expr.Hash{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Length:         concatKeyLen(l.hash.Fields),
+			Modulus:        uint32(len(elements)),
+			Seed:           l.hash.Seed,
+			Type:           expr.HashTypeJenkins,
+		})
+	default:
+		// [ numgen reg 1 = inc mod 2 ]
+		exprs = append(exprs, &expr.Numgen{
+			Register: 1,
+			Modulus:  uint32(len(elements)),
+			Type:     mode,
+			Offset:   0,
 		})
 	}
-	exprs = append(exprs, &expr.Numgen{
-		Register: 1,
-		Modulus:  uint32(len(l.chains)),
-		Type:     mode,
-		Offset:   0,
-	})
 
 	if err := nfr.conn.AddSet(set, elements); err != nil {
 		return nil, err
@@ -0,0 +1,374 @@
+package nftableslib
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// ObjectType defines the type of a named stateful object
+type ObjectType uint32
+
+// Types of named stateful objects
+const (
+	ObjectTypeCounter ObjectType = unix.NFT_OBJECT_COUNTER
+	ObjectTypeQuota   ObjectType = unix.NFT_OBJECT_QUOTA
+	ObjectTypeLimit   ObjectType = unix.NFT_OBJECT_LIMIT
+)
+
+// CounterValues defines packets and bytes accounted by a counter
+type CounterValues struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// Object defines a named stateful object of a table, only one of Counter, Quota or Limit must be specified.
+// When the object is created, Counter carries initial values of the counter, when the object is read,
+// Counter and Quota carry values accounted by the kernel.
+type Object struct {
+	Name    string
+	Counter *CounterValues
+	Quota   *Quota
+	Limit   *Limit
+}
+
+// Type returns the type of the object
+func (o *Object) Type() ObjectType {
+	switch {
+	case o.Quota != nil:
+		return ObjectTypeQuota
+	case o.Limit != nil:
+		return ObjectTypeLimit
+	}
+	return ObjectTypeCounter
+}
+
+// Validate checks parameters of the object
+func (o *Object) Validate() error {
+	if o.Name == "" {
+		return fmt.Errorf("name of object cannot be empty")
+	}
+	n := 0
+	if o.Counter != nil {
+		n++
+	}
+	if o.Quota != nil {
+		if err := o.Quota.Validate(); err != nil {
+			return err
+		}
+		n++
+	}
+	if o.Limit != nil {
+		if err := o.Limit.Validate(); err != nil {
+			return err
+		}
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("object %s must be either counter, quota or limit", o.Name)
+	}
+
+	return nil
+}
+
+// ObjectRef defines a reference to a named stateful object, example counter name "tenant-1". An object selected by
+// a map, quota name ip saddr map @m, cannot be referenced, google/nftables does not support objref expression with a set.
+type ObjectRef struct {
+	Type ObjectType
+	Name string
+}
+
+// Validate checks parameters of the reference to the object
+func (ref *ObjectRef) Validate() error {
+	switch ref.Type {
+	case ObjectTypeCounter, ObjectTypeQuota, ObjectTypeLimit:
+	default:
+		return fmt.Errorf("unsupported object type %d", ref.Type)
+	}
+	if ref.Name == "" {
+		return fmt.Errorf("name of referenced object cannot be empty")
+	}
+
+	return nil
+}
+
+// ObjectsInterface defines third level interface operating with named stateful objects
+type ObjectsInterface interface {
+	Objects() ObjectFuncs
+}
+
+// TableObjectsFuncs defines function returning Objects Interface of a table, TableFuncs returned by TablesInterface
+// implement it, example nft.Tables().(TableObjectsFuncs).TableObjects("filter", nftables.TableFamilyIPv4).
+type TableObjectsFuncs interface {
+	TableObjects(name string, familyType nftables.TableFamily) (ObjectsInterface, error)
+}
+
+// ObjectFuncs defines functions to operate with named stateful objects, CreateObject and DelObject queue
+// the object's programming until the connection is flushed, CreateObjectImm and DelObjectImm flush it.
+type ObjectFuncs interface {
+	CreateObject(*Object) error
+	CreateObjectImm(*Object) error
+	DelObject(string) error
+	DelObjectImm(string) error
+	GetObjects() ([]*Object, error)
+	GetObject(string) (*Object, error)
+	// ResetObject atomically reads and resets a counter or a quota, values before the reset are returned.
+	ResetObject(string) (*Object, error)
+}
+
+type nfObjects struct {
+	conn  NetNS
+	table *nftables.Table
+	sync.Mutex
+	// objs carries objects queued by CreateObject, they can be deleted before the connection is flushed.
+	objs map[string]*nftables.NamedObj
+}
+
+// Objects return a list of methods available for Objects operations
+func (nfo *nfObjects) Objects() ObjectFuncs {
+	return nfo
+}
+
+func (nfo *nfObjects) CreateObject(o *Object) error {
+	nfo.Lock()
+	defer nfo.Unlock()
+
+	return nfo.createObject(o)
+}
+
+func (nfo *nfObjects) CreateObjectImm(o *Object) error {
+	nfo.Lock()
+	defer nfo.Unlock()
+	if err := nfo.createObject(o); err != nil {
+		return err
+	}
+
+	return nfo.conn.Flush()
+}
+
+func (nfo *nfObjects) createObject(o *Object) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+	obj := &nftables.NamedObj{
+		Table: nfo.table,
+		Name:  o.Name,
+		Type:  nftables.ObjType(o.Type()),
+	}
+	switch {
+	case o.Counter != nil:
+		obj.Obj = &expr.Counter{
+			Packets: o.Counter.Packets,
+			Bytes:   o.Counter.Bytes,
+		}
+	case o.Quota != nil:
+		e, _ := getExprForQuota(o.Quota)
+		obj.Obj = e[0]
+	case o.Limit != nil:
+		e, _ := getExprForLimit(o.Limit)
+		obj.Obj = e[0]
+	}
+	conn, err := nfo.objectsConn()
+	if err != nil {
+		return err
+	}
+	conn.AddObj(obj)
+	nfo.objs[o.Name] = obj
+
+	return nil
+}
+
+func (nfo *nfObjects) DelObject(name string) error {
+	nfo.Lock()
+	defer nfo.Unlock()
+
+	return nfo.delObject(name)
+}
+
+func (nfo *nfObjects) DelObjectImm(name string) error {
+	nfo.Lock()
+	defer nfo.Unlock()
+	if err := nfo.delObject(name); err != nil {
+		return err
+	}
+
+	return nfo.conn.Flush()
+}
+
+func (nfo *nfObjects) delObject(name string) error {
+	conn, err := nfo.objectsConn()
+	if err != nil {
+		return err
+	}
+	// The object might not be programmed yet, but still pending in the connection's queue
+	obj, ok := nfo.objs[name]
+	if !ok {
+		if obj, err = nfo.getObject(name); err != nil {
+			return err
+		}
+	}
+	conn.DeleteObject(&nftables.NamedObj{
+		Table: nfo.table,
+		Name:  obj.Name,
+		Type:  obj.Type,
+	})
+	delete(nfo.objs, name)
+
+	return nil
+}
+
+// GetObjects returns counters, quotas and limits programmed on the host for a specific table.
+func (nfo *nfObjects) GetObjects() ([]*Object, error) {
+	conn, err := nfo.objectsConn()
+	if err != nil {
+		return nil, err
+	}
+	objs, err := conn.GetNamedObjects(nfo.table)
+	if err != nil {
+		return nil, err
+	}
+	objects := []*Object{}
+	for _, obj := range objs {
+		if o := objectFromNamedObj(obj); o != nil {
+			objects = append(objects, o)
+		}
+	}
+
+	return objects, nil
+}
+
+func (nfo *nfObjects) GetObject(name string) (*Object, error) {
+	obj, err := nfo.getObject(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return objectFromNamedObj(obj), nil
+}
+
+func (nfo *nfObjects) ResetObject(name string) (*Object, error) {
+	conn, err := nfo.objectsConn()
+	if err != nil {
+		return nil, err
+	}
+	obj, err := nfo.getObject(name)
+	if err != nil {
+		return nil, err
+	}
+	if obj.Type == nftables.ObjTypeLimit {
+		return nil, fmt.Errorf("limit object %s cannot be reset", name)
+	}
+	ro, err := conn.ResetObject(&nftables.NamedObj{
+		Table: nfo.table,
+		Name:  obj.Name,
+		Type:  obj.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+	o := objectFromNamedObj(ro)
+	if o == nil {
+		return nil, fmt.Errorf("failed to reset object %s", name)
+	}
+
+	return o, nil
+}
+
+// objectsConn returns the connection's interface operating with named stateful objects
+func (nfo *nfObjects) objectsConn() (ObjectsConn, error) {
+	conn, ok := nfo.conn.(ObjectsConn)
+	if !ok {
+		return nil, fmt.Errorf("named objects are not supported by the connection")
+	}
+
+	return conn, nil
+}
+
+// getObject looks up the object by its name among objects programmed on the host
+func (nfo *nfObjects) getObject(name string) (*nftables.NamedObj, error) {
+	conn, err := nfo.objectsConn()
+	if err != nil {
+		return nil, err
+	}
+	objs, err := conn.GetNamedObjects(nfo.table)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		o, ok := obj.(*nftables.NamedObj)
+		if !ok || o.Name != name {
+			continue
+		}
+		switch o.Type {
+		case nftables.ObjTypeCounter, nftables.ObjTypeQuota, nftables.ObjTypeLimit:
+			return o, nil
+		}
+	}
+
+	return nil, fmt.Errorf("object %s does not exist", name)
+}
+
+// objectFromNamedObj converts nftables object to Object, nil is returned for unsupported objects
+func objectFromNamedObj(obj nftables.Obj) *Object {
+	no, ok := obj.(*nftables.NamedObj)
+	if !ok {
+		return nil
+	}
+	o := &Object{Name: no.Name}
+	switch e := no.Obj.(type) {
+	case *expr.Counter:
+		o.Counter = &CounterValues{
+			Packets: e.Packets,
+			Bytes:   e.Bytes,
+		}
+	case *expr.Quota:
+		o.Quota = &Quota{
+			Bytes:    e.Bytes,
+			Consumed: e.Consumed,
+			Over:     e.Over,
+		}
+	case *expr.Limit:
+		o.Limit = &Limit{
+			Rate:  e.Rate,
+			Unit:  LimitUnit(e.Unit),
+			Burst: e.Burst,
+			Bytes: e.Type == expr.LimitTypePktBytes,
+			Over:  e.Over,
+		}
+	default:
+		return nil
+	}
+
+	return o
+}
+
+func getExprForObjectRefs(refs []*ObjectRef) ([]expr.Any, error) {
+	re := []expr.Any{}
+	for _, ref := range refs {
+		if ref == nil {
+			// Skipping nil pointers
+			continue
+		}
+		if err := ref.Validate(); err != nil {
+			return nil, err
+		}
+		// [ objref type 1 name tenant-1 ]
+		re = append(re, &expr.Objref{
+			Type: int(ref.Type),
+			Name: ref.Name,
+		})
+	}
+
+	return re, nil
+}
+
+func newObjects(conn NetNS, t *nftables.Table) ObjectsInterface {
+	return &nfObjects{
+		conn:  conn,
+		table: t,
+		objs:  make(map[string]*nftables.NamedObj),
+	}
+}
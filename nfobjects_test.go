@@ -0,0 +1,176 @@
+package nftableslib
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/nftables"
+)
+
+func TestObjectValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		object   *Object
+		wantType ObjectType
+		success  bool
+	}{
+		{
+			name:     "Counter",
+			object:   &Object{Name: "tenant-1", Counter: &CounterValues{}},
+			wantType: ObjectTypeCounter,
+			success:  true,
+		},
+		{
+			name:     "Quota",
+			object:   &Object{Name: "tenant-1", Quota: &Quota{Bytes: 1024, Over: true}},
+			wantType: ObjectTypeQuota,
+			success:  true,
+		},
+		{
+			name:     "Limit",
+			object:   &Object{Name: "tenant-1", Limit: &Limit{Rate: 10, Unit: LimitPerMinute}},
+			wantType: ObjectTypeLimit,
+			success:  true,
+		},
+		{
+			name:    "No name",
+			object:  &Object{Counter: &CounterValues{}},
+			success: false,
+		},
+		{
+			name:    "Counter and quota",
+			object:  &Object{Name: "tenant-1", Counter: &CounterValues{}, Quota: &Quota{Bytes: 1024}},
+			success: false,
+		},
+		{
+			name:    "Invalid limit",
+			object:  &Object{Name: "tenant-1", Limit: &Limit{Unit: LimitPerMinute}},
+			success: false,
+		},
+		{
+			name:    "Neither counter, quota nor limit",
+			object:  &Object{Name: "tenant-1"},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		err := tt.object.Validate()
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if tt.object.Type() != tt.wantType {
+			t.Errorf("Test \"%s\" failed, type of object is %d but supposed to be %d", tt.name, tt.object.Type(), tt.wantType)
+		}
+	}
+}
+
+func TestCreateObject(t *testing.T) {
+	tests := []struct {
+		name   string
+		object *Object
+	}{
+		{
+			name:   "Counter",
+			object: &Object{Name: "tenant-1", Counter: &CounterValues{Packets: 10, Bytes: 1500}},
+		},
+		{
+			name:   "Quota",
+			object: &Object{Name: "tenant-1", Quota: &Quota{Bytes: 1024, Consumed: 512}},
+		},
+		{
+			name:   "Limit",
+			object: &Object{Name: "tenant-1", Limit: &Limit{Rate: 1024, Unit: LimitPerSecond, Burst: 512, Bytes: true, Over: true}},
+		},
+	}
+	for _, tt := range tests {
+		conn := &objectsConn{}
+		nfo := newObjects(conn, &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv4})
+		if err := nfo.Objects().CreateObject(tt.object); err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(conn.objs) != 1 {
+			t.Errorf("Test \"%s\" failed, %d objects are added but supposed to be 1", tt.name, len(conn.objs))
+			continue
+		}
+		o := objectFromNamedObj(conn.objs[0])
+		if !reflect.DeepEqual(o, tt.object) {
+			t.Errorf("Test \"%s\" failed, added object %+v does not match %+v", tt.name, o, tt.object)
+		}
+		if conn.flushed != 0 {
+			t.Errorf("Test \"%s\" failed, connection is flushed but the object is supposed to be queued", tt.name)
+		}
+	}
+}
+
+func TestDelObjectPending(t *testing.T) {
+	conn := &objectsConn{}
+	nfo := newObjects(conn, &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv4})
+	if err := nfo.Objects().CreateObject(&Object{Name: "tenant-1", Quota: &Quota{Bytes: 1024}}); err != nil {
+		t.Fatalf("failed to create object with error: %+v", err)
+	}
+	// The object is not programmed yet, the connection does not return it
+	conn.objs = nil
+	if err := nfo.Objects().DelObjectImm("tenant-1"); err != nil {
+		t.Fatalf("failed to delete pending object with error: %+v", err)
+	}
+	if !reflect.DeepEqual(conn.deleted, []string{"tenant-1"}) || conn.flushed != 1 {
+		t.Fatalf("deleted objects %v and %d flushes do not match [tenant-1] and 1 flush", conn.deleted, conn.flushed)
+	}
+	if err := nfo.Objects().DelObject("tenant-1"); err == nil {
+		t.Fatalf("deleting removed object succeeded but supposed to fail")
+	}
+}
+
+func TestObjectsUnsupportedConn(t *testing.T) {
+	nfo := newObjects(&countersConn{}, &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv4})
+	if err := nfo.Objects().CreateObject(&Object{Name: "tenant-1", Counter: &CounterValues{}}); err == nil {
+		t.Errorf("creating object over connection without objects support succeeded but supposed to fail")
+	}
+	if _, err := nfo.Objects().GetObjects(); err == nil {
+		t.Errorf("getting objects over connection without objects support succeeded but supposed to fail")
+	}
+}
+
+// nftables.Conn supports named objects
+var _ ObjectsConn = (*nftables.Conn)(nil)
+
+// TableFuncs support objects of a table
+var _ TableObjectsFuncs = (*nfTables)(nil)
+
+// objectsConn records objects added to and deleted from the connection
+type objectsConn struct {
+	NetNS
+	objs    []nftables.Obj
+	deleted []string
+	flushed int
+}
+
+func (c *objectsConn) AddObj(o nftables.Obj) nftables.Obj {
+	c.objs = append(c.objs, o)
+	return o
+}
+
+func (c *objectsConn) DeleteObject(o nftables.Obj) {
+	c.deleted = append(c.deleted, o.(*nftables.NamedObj).Name)
+}
+
+func (c *objectsConn) GetNamedObjects(*nftables.Table) ([]nftables.Obj, error) {
+	return c.objs, nil
+}
+
+func (c *objectsConn) ResetObject(o nftables.Obj) (nftables.Obj, error) {
+	return o, nil
+}
+
+func (c *objectsConn) Flush() error {
+	c.flushed++
+	return nil
+}
@@ -0,0 +1,120 @@
+package nftableslib
+
+import (
+	"fmt"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+)
+
+const (
+	// Offsets of fields in ARP header for ethernet and ipv4 addresses
+	arpOffsetOperation = 6
+	arpOffsetSHA       = 8
+	arpOffsetSPA       = 14
+	arpOffsetTHA       = 18
+	arpOffsetTPA       = 24
+)
+
+func createARP(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, error) {
+	re := []expr.Any{}
+	e := []expr.Any{}
+	sets := make([]*nfSet, 0)
+	var set *nfSet
+	var s []*nfSet
+	var err error
+
+	if family != nftables.TableFamilyARP {
+		return nil, nil, fmt.Errorf("ARP rule is not supported in %s table", familyName(family))
+	}
+	if err := rule.ARP.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if rule.ARP.Operation != nil {
+		re = append(re, getExprForARPOperation(*rule.ARP.Operation, rule.ARP.RelOp)...)
+	}
+	if rule.ARP.SHA == nil && rule.ARP.THA == nil && rule.ARP.SPA == nil && rule.ARP.TPA == nil {
+		return re, sets, nil
+	}
+	// Offsets of addresses are valid only for ethernet and ipv4 ARP packets
+	re = append(re, getExprForARPEtherIPv4()...)
+	for _, mac := range []struct {
+		spec   *MACAddrSpec
+		offset uint32
+	}{
+		{rule.ARP.SHA, arpOffsetSHA},
+		{rule.ARP.THA, arpOffsetTHA},
+	} {
+		if mac.spec == nil {
+			continue
+		}
+		if e, set, err = processMACAddr(mac.spec, expr.PayloadBaseNetworkHeader, mac.offset); err != nil {
+			return nil, nil, err
+		}
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+	for _, ip := range []struct {
+		spec   *IPAddrSpec
+		offset uint32
+	}{
+		{rule.ARP.SPA, arpOffsetSPA},
+		{rule.ARP.TPA, arpOffsetTPA},
+	} {
+		if ip.spec == nil {
+			continue
+		}
+		if e, s, err = processIPAddrAtOffset(nftables.TableFamilyIPv4, ip.spec, ip.offset, ip.spec.RelOp); err != nil {
+			return nil, nil, err
+		}
+		sets = append(sets, s...)
+		re = append(re, e...)
+	}
+
+	return re, sets, nil
+}
+
+func getExprForARPOperation(op uint16, relOp Operator) []expr.Any {
+	cmpOp := expr.CmpOpEq
+	if relOp == NEQ {
+		cmpOp = expr.CmpOpNeq
+	}
+	// [ payload load 2b @ network header + 6 => reg 1 ]
+	// [ cmp eq reg 1 0x00000100 ]
+	return []expr.Any{
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseNetworkHeader,
+			Offset:       arpOffsetOperation,
+			Len:          2,
+		},
+		&expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(op),
+		},
+	}
+}
+
+// getExprForARPEtherIPv4 returns expression to match ARP packets with ethernet hardware type,
+// ipv4 protocol type, 6 bytes hardware address length and 4 bytes protocol address length.
+func getExprForARPEtherIPv4() []expr.Any {
+	// [ payload load 6b @ network header + 0 => reg 1 ]
+	// [ cmp eq reg 1 0x00080100 0x00000406 ]
+	return []expr.Any{
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseNetworkHeader,
+			Offset:       0,
+			Len:          6,
+		},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     []byte{0x0, 0x1, 0x8, 0x0, 0x6, 0x4},
+		},
+	}
+}
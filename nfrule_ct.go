@@ -0,0 +1,258 @@
+package nftableslib
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Length of connection tracking labels bitmask
+	ctLabelsLen = 16
+)
+
+// ctDirection returns the value of Direction of expr.Ct, the kernel reads the direction as a single byte
+// while it is marshaled as 4 bytes in the network byte order, the direction is moved to the first byte.
+func ctDirection(direction uint8) uint32 {
+	return uint32(direction) << 24
+}
+
+func validateConntrack(family nftables.TableFamily, ct *Conntrack) error {
+	if ct.Direction != CTDirectionOriginal && ct.Direction != CTDirectionReply {
+		return fmt.Errorf("invalid conntrack direction %d", ct.Direction)
+	}
+	if ct.Mask != nil && len(ct.Mask) != len(ct.Value) {
+		return fmt.Errorf("length of conntrack mask %d does not match length of value %d", len(ct.Mask), len(ct.Value))
+	}
+	length := 0
+	ordered := false
+	switch ct.Key {
+	case unix.NFT_CT_STATE, unix.NFT_CT_STATUS:
+		length = 4
+	case unix.NFT_CT_LABELS:
+		length = ctLabelsLen
+	case unix.NFT_CT_MARK, unix.NFT_CT_EXPIRATION:
+		length = 4
+		ordered = true
+	case unix.NFT_CT_ZONE:
+		length = 2
+		ordered = true
+	case unix.NFT_CT_DIRECTION, unix.NFT_CT_L3PROTOCOL, unix.NFT_CT_PROTOCOL:
+		length = 1
+	case unix.NFT_CT_PROTO_SRC, unix.NFT_CT_PROTO_DST:
+		length = 2
+		ordered = true
+	case unix.NFT_CT_SRC, unix.NFT_CT_DST:
+		// Conntrack addresses are resolved only by ip, ip6 and inet tables
+		switch family {
+		case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6, nftables.TableFamilyINet:
+		default:
+			return fmt.Errorf("conntrack addresses are not supported in %s table", familyName(family))
+		}
+		length = len(ct.Value)
+		switch {
+		case length != 4 && length != 16:
+			return fmt.Errorf("invalid length %d of conntrack address", length)
+		case family == nftables.TableFamilyIPv4 && length != 4:
+			return fmt.Errorf("conntrack address must be ipv4 address in %s table", familyName(family))
+		case family == nftables.TableFamilyIPv6 && length != 16:
+			return fmt.Errorf("conntrack address must be ipv6 address in %s table", familyName(family))
+		}
+	case unix.NFT_CT_EVENTMASK:
+		return fmt.Errorf("conntrack key eventmask cannot be matched")
+	default:
+		return fmt.Errorf("unsupported conntrack key %d", ct.Key)
+	}
+	if len(ct.Value) != length {
+		return fmt.Errorf("invalid length %d of value of conntrack key %d, expected %d", len(ct.Value), ct.Key, length)
+	}
+	if ct.Mask != nil {
+		switch ct.Key {
+		case unix.NFT_CT_STATE, unix.NFT_CT_STATUS, unix.NFT_CT_LABELS:
+			return fmt.Errorf("mask cannot be used with bitmask conntrack key %d", ct.Key)
+		}
+	}
+	if ct.Direction != CTDirectionOriginal {
+		// google/nftables sends the direction only for keys of the tuple, the other keys would match both directions
+		switch ct.Key {
+		case unix.NFT_CT_SRC, unix.NFT_CT_DST, unix.NFT_CT_PROTO_SRC, unix.NFT_CT_PROTO_DST:
+		default:
+			return fmt.Errorf("direction cannot be used with conntrack key %d", ct.Key)
+		}
+	}
+
+	return validateRelOp(ct.RelOp, ordered)
+}
+
+func getExprForConntracks(family nftables.TableFamily, cts []*Conntrack) ([]expr.Any, error) {
+	re := []expr.Any{}
+	for _, ct := range cts {
+		if ct == nil {
+			// Skipping nil pointers
+			continue
+		}
+		if err := validateConntrack(family, ct); err != nil {
+			return nil, err
+		}
+		e, err := getExprForConntrack(family, ct)
+		if err != nil {
+			return nil, err
+		}
+		re = append(re, e...)
+	}
+
+	return re, nil
+}
+
+func getExprForConntrack(family nftables.TableFamily, ct *Conntrack) ([]expr.Any, error) {
+	re := []expr.Any{}
+	key := expr.CtKey(ct.Key)
+	switch ct.Key {
+	case unix.NFT_CT_STATE, unix.NFT_CT_STATUS, unix.NFT_CT_LABELS:
+		//	[ ct load state => reg 1 ]
+		//	[ bitwise reg 1 = (reg=1 & 0x00000008 ) ^ 0x00000000 ]
+		//	[ cmp neq reg 1 0x00000000 ]
+		// Bitmask matches when any of its bits is set, with NEQ none of the bits must be set
+		cmpOp := expr.CmpOpNeq
+		if ct.RelOp == NEQ {
+			cmpOp = expr.CmpOpEq
+		}
+		re = append(re, &expr.Ct{Key: key, Register: 1})
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            uint32(len(ct.Value)),
+			Mask:           ct.Value,
+			Xor:            make([]byte, len(ct.Value)),
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     make([]byte, len(ct.Value)),
+		})
+		return re, nil
+	case unix.NFT_CT_SRC, unix.NFT_CT_DST:
+		if family != nftables.TableFamilyIPv4 && family != nftables.TableFamilyIPv6 {
+			// Outside of ip and ip6 tables the address is loaded as 16 bytes, the connection's
+			// l3 protocol must be checked to not match the first 4 bytes of ipv6 address.
+			// [ ct load l3protocol => reg 1 ]
+			// [ cmp eq reg 1 0x00000002 ]
+			l3proto := byte(unix.NFPROTO_IPV6)
+			if len(ct.Value) == 4 {
+				l3proto = unix.NFPROTO_IPV4
+			}
+			re = append(re, &expr.Ct{Key: expr.CtKeyL3PROTOCOL, Register: 1})
+			re = append(re, &expr.Cmp{
+				Op:       expr.CmpOpEq,
+				Register: 1,
+				Data:     []byte{l3proto},
+			})
+		}
+	}
+	// [ ct load mark => reg 1 ]
+	// [ ct load src original => reg 1 ]
+	re = append(re, &expr.Ct{Key: key, Register: 1, Direction: ctDirection(ct.Direction)})
+	if ct.Mask != nil {
+		// [ bitwise reg 1 = (reg=1 & 0x0000ffff ) ^ 0x00000000 ]
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            uint32(len(ct.Mask)),
+			Mask:           ct.Mask,
+			Xor:            make([]byte, len(ct.Mask)),
+		})
+	}
+	switch ct.Key {
+	case unix.NFT_CT_MARK, unix.NFT_CT_EXPIRATION, unix.NFT_CT_ZONE:
+		// [ cmp gt reg 1 0x00000010 ]
+		re = append(re, getExprForHostOrderCmp(ct.RelOp, ct.Value)...)
+	default:
+		// [ cmp eq reg 1 0x0100007f ]
+		re = append(re, &expr.Cmp{
+			Op:       getCmpOp(ct.RelOp),
+			Register: 1,
+			Data:     ct.Value,
+		})
+	}
+
+	return re, nil
+}
+
+func getExprForCTSet(nfr *nfRules, ct *ctset) ([]expr.Any, error) {
+	re := []expr.Any{}
+	switch {
+	case ct.zones != nil:
+		zoneType := nftables.TypeInteger
+		zoneType.Bytes = 2
+		set := &nftables.Set{
+			Table:     nfr.table,
+			Anonymous: true,
+			Constant:  true,
+			IsMap:     true,
+			KeyType:   nftables.TypeIFName,
+			DataType:  zoneType,
+		}
+		names := make([]string, 0, len(ct.zones))
+		for name := range ct.zones {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		elements := make([]nftables.SetElement, len(names))
+		for i, name := range names {
+			elements[i].Key = ifname(name)
+			elements[i].Val = binaryutil.NativeEndian.PutUint16(ct.zones[name])
+		}
+		if err := nfr.conn.AddSet(set, elements); err != nil {
+			return nil, err
+		}
+		// [ meta load iifname => reg 1 ]
+		// [ lookup reg 1 set __map%d dreg 1 ]
+		re = append(re, &expr.Meta{Key: expr.MetaKeyIIFNAME, Register: 1})
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			DestRegister:   1,
+			IsDestRegSet:   true,
+			SetID:          set.ID,
+			SetName:        set.Name,
+		})
+	case ct.fromMetaMark:
+		// [ meta load mark => reg 1 ]
+		re = append(re, &expr.Meta{Key: expr.MetaKeyMARK, Register: 1})
+		if ct.mask != nil {
+			// [ bitwise reg 1 = (reg=1 & 0x0000ffff ) ^ 0x00000000 ]
+			re = append(re, &expr.Bitwise{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Len:            uint32(len(ct.mask)),
+				Mask:           ct.mask,
+				Xor:            make([]byte, len(ct.mask)),
+			})
+		}
+	case ct.mask != nil:
+		// [ ct load mark => reg 1 ]
+		// [ bitwise reg 1 = (reg=1 & 0xffff0000 ) ^ 0x00000001 ]
+		mask := make([]byte, len(ct.mask))
+		for i := range ct.mask {
+			mask[i] = ^ct.mask[i]
+		}
+		re = append(re, &expr.Ct{Key: expr.CtKey(ct.key), Register: 1})
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            uint32(len(ct.mask)),
+			Mask:           mask,
+			Xor:            ct.value,
+		})
+	default:
+		// [ immediate reg 1 0x00000001 ]
+		re = append(re, &expr.Immediate{Register: 1, Data: ct.value})
+	}
+	// [ ct set mark with reg 1 ]
+	re = append(re, &expr.Ct{Key: expr.CtKey(ct.key), Register: 1, SourceRegister: true})
+
+	return re, nil
+}
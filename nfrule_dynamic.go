@@ -9,31 +9,27 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-func getExprForDynamic(l3proto nftables.TableFamily, dynamic *Dynamic) ([]expr.Any, error) {
+func getExprForDynamic(family nftables.TableFamily, dynamic *Dynamic) ([]expr.Any, error) {
 	// If dynamic does not carry a populated Set or Map, return error
 	if dynamic.SetRef == nil {
 		return nil, fmt.Errorf("reference to set or map cannot be nil")
 	}
 
-	var l3OffsetSrc, l3OffsetDst, l3AddrLen /*, l4ProtoOffset*/ uint32
+	var l3OffsetSrc, l3OffsetDst, l3AddrLen uint32
 	l4OffsetSrc := uint32(0)
 	l4OffsetDst := uint32(2)
 	re := []expr.Any{}
 
-	switch l3proto {
-	case nftables.TableFamilyIPv4:
-		l3OffsetSrc = 12
-		l3OffsetDst = 16
-		l3AddrLen = 4
-		// l4ProtoOffset = 9
-	case nftables.TableFamilyIPv6:
-		l3OffsetSrc = 8
-		l3OffsetDst = 24
-		l3AddrLen = 16
-		// l4ProtoOffset = 6
-	default:
-		return nil, fmt.Errorf("unsupported table family %d", l3proto)
+	l3proto, err := getMatchL3Family(family, dynamic.Match, dynamic.L3Proto)
+	if err != nil {
+		return nil, err
+	}
+	if !isMultiFamily(l3proto) {
+		if l3OffsetSrc, l3OffsetDst, l3AddrLen, _, err = l3Offsets(l3proto); err != nil {
+			return nil, err
+		}
 	}
+	re = append(re, getExprForL3Guard(family, l3proto)...)
 
 	switch dynamic.Match {
 	case MatchTypeL3Src:
@@ -70,25 +66,70 @@ func getExprForDynamic(l3proto nftables.TableFamily, dynamic *Dynamic) ([]expr.A
 	if len(re) == 0 {
 		return nil, fmt.Errorf("no valid matching criteria was found")
 	}
-	re = append(re, &expr.Immediate{
-		// Value of register must match to the value of SrcRegData
-		Register: 2,
-		Data:     binaryutil.BigEndian.PutUint32(dynamic.Key),
-	})
 	de := &expr.Dynset{
 		SrcRegKey: 1,
+		Operation: dynamic.Op,
+		SetID:     dynamic.SetRef.ID,
+		SetName:   dynamic.SetRef.Name,
+		Invert:    dynamic.Invert,
+	}
+	// Only elements of a map carry the data, Key is added to the map as the data of the element.
+	if dynamic.SetRef.IsMap {
+		re = append(re, &expr.Immediate{
+			// Value of register must match to the value of SrcRegData
+			Register: 2,
+			Data:     binaryutil.BigEndian.PutUint32(dynamic.Key),
+		})
 		// Value of SrcRegData must match to the value of expr.Immediate's Register
-		SrcRegData: 2,
-		Operation:  dynamic.Op,
-		SetID:      dynamic.SetRef.ID,
-		SetName:    dynamic.SetRef.Name,
-		Invert:     dynamic.Invert,
+		de.SrcRegData = 2
 	}
 	// Entry timeout only makes sense only if  Operation is Update
 	if dynamic.Op == unix.NFT_DYNSET_OP_UPDATE {
 		de.Timeout = dynamic.Timeout
 	}
+	if de.Exprs, err = getExprForDynamicStateful(dynamic); err != nil {
+		return nil, err
+	}
 	re = append(re, de)
 
 	return re, nil
 }
+
+// getExprForDynamicStateful returns stateful expressions attached to the element of the set
+func getExprForDynamicStateful(dynamic *Dynamic) ([]expr.Any, error) {
+	re := []expr.Any{}
+	if dynamic.Counter != nil {
+		// [ counter pkts 0 bytes 0 ]
+		re = append(re, getExprForCounter()...)
+	}
+	if dynamic.Limit != nil {
+		e, err := getExprForLimit(dynamic.Limit)
+		if err != nil {
+			return nil, err
+		}
+		re = append(re, e...)
+	}
+	if dynamic.Quota != nil {
+		e, err := getExprForQuota(dynamic.Quota)
+		if err != nil {
+			return nil, err
+		}
+		re = append(re, e...)
+	}
+	if dynamic.ConnLimit != nil {
+		if dynamic.ConnLimit.Count == 0 {
+			return nil, fmt.Errorf("count of connlimit cannot be 0")
+		}
+		// [ connlimit count 20 flags 1 ]
+		cl := &expr.Connlimit{Count: dynamic.ConnLimit.Count}
+		if dynamic.ConnLimit.Over {
+			cl.Flags = expr.NFT_CONNLIMIT_F_INV
+		}
+		re = append(re, cl)
+	}
+	if len(re) == 0 {
+		return nil, nil
+	}
+
+	return re, nil
+}
@@ -0,0 +1,241 @@
+package nftableslib
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Offset and length of the value of maximum segment size option
+	tcpOptionMSSOffset = 2
+	tcpOptionMSSLen    = 2
+	// Offsets of fields in routing header
+	ipv6RoutingOffsetType         = 2
+	ipv6RoutingOffsetSegmentsLeft = 3
+	// Offsets of fields in fragment header
+	ipv6FragmentOffsetOffset = 2
+	ipv6FragmentOffsetFlags  = 3
+	ipv6FragmentOffsetID     = 4
+)
+
+func createTCPOption(family nftables.TableFamily, option *TCPOption) ([]expr.Any, error) {
+	if family == nftables.TableFamilyARP {
+		return nil, fmt.Errorf("TCP option is not supported in %s table", familyName(family))
+	}
+	if err := option.Validate(); err != nil {
+		return nil, err
+	}
+	cmpOp := getCmpOp(option.RelOp)
+	// [ meta load l4proto => reg 1 ]
+	// [ cmp eq reg 1 0x00000006 ]
+	re := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     []byte{unix.IPPROTO_TCP},
+		},
+	}
+	switch {
+	case option.Kind != nil:
+		// [ exthdr load tcpopt 1b @ 2 + 0 present => reg 1 ]
+		// [ cmp eq reg 1 0x00000001 ]
+		re = append(re, &expr.Exthdr{
+			Op:           expr.ExthdrOpTcpopt,
+			DestRegister: 1,
+			Type:         *option.Kind,
+			Offset:       0,
+			Len:          1,
+			Flags:        unix.NFT_EXTHDR_F_PRESENT,
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{0x1},
+		})
+	case option.MSS != nil:
+		// [ exthdr load tcpopt 2b @ 2 + 2 => reg 1 ]
+		// [ cmp eq reg 1 0x0000b405 ]
+		re = append(re, &expr.Exthdr{
+			Op:           expr.ExthdrOpTcpopt,
+			DestRegister: 1,
+			Type:         TCPOptionMSS,
+			Offset:       tcpOptionMSSOffset,
+			Len:          tcpOptionMSSLen,
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(*option.MSS),
+		})
+	}
+
+	return re, nil
+}
+
+func getExprForTCPOptionSet(option *TCPOption) []expr.Any {
+	re := []expr.Any{}
+	switch {
+	case option.SetMSS != nil:
+		// [ immediate reg 1 0x00005005 ]
+		re = append(re, &expr.Immediate{
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(*option.SetMSS),
+		})
+	case option.SetMSSToPMTU:
+		// [ rt load tcpmss => reg 1 ]
+		re = append(re, &expr.Rt{
+			Register: 1,
+			Key:      expr.RtTCPMSS,
+		})
+	default:
+		return re
+	}
+	// [ exthdr write tcpopt reg 1 => 2b @ 2 + 2 ]
+	re = append(re, &expr.Exthdr{
+		Op:             expr.ExthdrOpTcpopt,
+		SourceRegister: 1,
+		Type:           TCPOptionMSS,
+		Offset:         tcpOptionMSSOffset,
+		Len:            tcpOptionMSSLen,
+	})
+
+	return re
+}
+
+func processIPv6ExtHdr(hdr *IPv6ExtHdr) ([]expr.Any, *nfSet) {
+	var nfset *nfSet
+	cmpOp := getCmpOp(hdr.RelOp)
+	excl := false
+	if hdr.RelOp == NEQ {
+		excl = true
+	}
+	load := func(offset, length uint32) *expr.Exthdr {
+		return &expr.Exthdr{
+			Op:           expr.ExthdrOpIpv6,
+			DestRegister: 1,
+			Type:         hdr.Type,
+			Offset:       offset,
+			Len:          length,
+		}
+	}
+	re := []expr.Any{}
+	if hdr.RoutingType == nil && len(hdr.SegmentsLeft) == 0 &&
+		hdr.FragmentOffset == nil && hdr.MoreFragments == nil && hdr.FragmentID == nil {
+		// Only presence of the extension header is matched
+		// [ exthdr load ipv6 1b @ 0 + 0 present => reg 1 ]
+		// [ cmp eq reg 1 0x00000001 ]
+		e := load(0, 1)
+		e.Flags = unix.NFT_EXTHDR_F_PRESENT
+		re = append(re, e)
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{0x1},
+		})
+		return re, nil
+	}
+	if hdr.RoutingType != nil {
+		// [ exthdr load ipv6 1b @ 43 + 2 => reg 1 ]
+		// [ cmp eq reg 1 0x00000000 ]
+		re = append(re, load(ipv6RoutingOffsetType, 1))
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{*hdr.RoutingType},
+		})
+	}
+	switch {
+	case len(hdr.SegmentsLeft) == 1:
+		// [ exthdr load ipv6 1b @ 43 + 3 => reg 1 ]
+		// [ cmp eq reg 1 0x00000002 ]
+		re = append(re, load(ipv6RoutingOffsetSegmentsLeft, 1))
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{hdr.SegmentsLeft[0]},
+		})
+	case len(hdr.SegmentsLeft) > 1:
+		keyType := nftables.TypeInteger
+		keyType.Bytes = 1
+		set := &nftables.Set{
+			Anonymous: false,
+			Constant:  true,
+			Name:      getSetName(),
+			ID:        uint32(rand.Intn(0xffff)),
+			KeyType:   keyType,
+		}
+		se := make([]nftables.SetElement, len(hdr.SegmentsLeft))
+		for i, s := range hdr.SegmentsLeft {
+			se[i].Key = []byte{s}
+		}
+		nfset = &nfSet{set: set, elements: se}
+		// [ exthdr load ipv6 1b @ 43 + 3 => reg 1 ]
+		// [ lookup reg 1 set __set%d ]
+		re = append(re, load(ipv6RoutingOffsetSegmentsLeft, 1))
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          set.ID,
+			SetName:        set.Name,
+		})
+	}
+	if hdr.FragmentOffset != nil {
+		// Fragment offset occupies 13 high bits of 2 bytes field
+		// [ exthdr load ipv6 2b @ 44 + 2 => reg 1 ]
+		// [ bitwise reg 1 = ( reg 1 & 0x0000f8ff ) ^ 0x00000000 ]
+		// [ cmp eq reg 1 0x00000800 ]
+		re = append(re, load(ipv6FragmentOffsetOffset, 2))
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            2,
+			Mask:           binaryutil.BigEndian.PutUint16(0xfff8),
+			Xor:            []byte{0x0, 0x0},
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(*hdr.FragmentOffset << 3),
+		})
+	}
+	if hdr.MoreFragments != nil {
+		// [ exthdr load ipv6 1b @ 44 + 3 => reg 1 ]
+		// [ bitwise reg 1 = ( reg 1 & 0x00000001 ) ^ 0x00000000 ]
+		// [ cmp eq reg 1 0x00000001 ]
+		mf := []byte{0x0}
+		if *hdr.MoreFragments {
+			mf = []byte{0x1}
+		}
+		re = append(re, load(ipv6FragmentOffsetFlags, 1))
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            1,
+			Mask:           []byte{0x1},
+			Xor:            []byte{0x0},
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     mf,
+		})
+	}
+	if hdr.FragmentID != nil {
+		// [ exthdr load ipv6 4b @ 44 + 4 => reg 1 ]
+		// [ cmp eq reg 1 0x39300000 ]
+		re = append(re, load(ipv6FragmentOffsetID, 4))
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint32(*hdr.FragmentID),
+		})
+	}
+
+	return re, nfset
+}
@@ -0,0 +1,130 @@
+package nftableslib
+
+import (
+	"math/rand"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+)
+
+func createIntf(intf *Interface) ([]expr.Any, []*nfSet, error) {
+	re := []expr.Any{}
+	sets := make([]*nfSet, 0)
+
+	if err := intf.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if intf.Input != nil {
+		e, set := processIntf(intf.Input, true)
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+	if intf.Output != nil {
+		e, set := processIntf(intf.Output, false)
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+
+	return re, sets, nil
+}
+
+// intfMetaKey returns meta key and set's key type for a specific attribute of input or output interface
+func intfMetaKey(key IntfKey, input bool) (expr.MetaKey, nftables.SetDatatype) {
+	switch key {
+	case IntfKeyIndex:
+		if input {
+			return expr.MetaKeyIIF, nftables.TypeIFIndex
+		}
+		return expr.MetaKeyOIF, nftables.TypeIFIndex
+	case IntfKeyGroup:
+		if input {
+			return expr.MetaKeyIIFGROUP, nftables.TypeDevGroup
+		}
+		return expr.MetaKeyOIFGROUP, nftables.TypeDevGroup
+	}
+	if input {
+		return expr.MetaKeyIIFNAME, nftables.TypeIFName
+	}
+	return expr.MetaKeyOIFNAME, nftables.TypeIFName
+}
+
+func processIntf(intf *IntfSpec, input bool) ([]expr.Any, *nfSet) {
+	var keys [][]byte
+	key := intf.Key
+	switch {
+	case len(intf.Name) == 1:
+		if input {
+			return inputIntfByName(intf.Name[0], intf.RelOp), nil
+		}
+		return outputIntfByName(intf.Name[0], intf.RelOp), nil
+	case len(intf.Name) != 0:
+		key = IntfKeyName
+		for _, name := range intf.Name {
+			keys = append(keys, ifname(name))
+		}
+	case len(intf.Index) != 0:
+		key = IntfKeyIndex
+		for _, index := range intf.Index {
+			keys = append(keys, binaryutil.NativeEndian.PutUint32(index))
+		}
+	case len(intf.Group) != 0:
+		key = IntfKeyGroup
+		for _, group := range intf.Group {
+			keys = append(keys, binaryutil.NativeEndian.PutUint32(group))
+		}
+	}
+	metaKey, keyType := intfMetaKey(key, input)
+	// [ meta load iif => reg 1 ]
+	re := []expr.Any{&expr.Meta{Key: metaKey, Register: 1}}
+	excl := false
+	if intf.RelOp == NEQ {
+		excl = true
+	}
+	if intf.SetRef != nil {
+		// [ lookup reg 1 set interfaces ]
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          intf.SetRef.ID,
+			SetName:        intf.SetRef.Name,
+		})
+		return re, nil
+	}
+	if len(keys) == 1 {
+		// [ cmp eq reg 1 0x00000002 ]
+		cmpOp := expr.CmpOpEq
+		if excl {
+			cmpOp = expr.CmpOpNeq
+		}
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     keys[0],
+		})
+		return re, nil
+	}
+	set := &nftables.Set{
+		Anonymous: false,
+		Constant:  true,
+		Name:      getSetName(),
+		ID:        uint32(rand.Intn(0xffff)),
+		KeyType:   keyType,
+	}
+	se := make([]nftables.SetElement, 0, len(keys))
+	for _, k := range keys {
+		se = append(se, nftables.SetElement{Key: k})
+	}
+	re = append(re, &expr.Lookup{
+		SourceRegister: 1,
+		Invert:         excl,
+		SetID:          set.ID,
+		SetName:        set.Name,
+	})
+
+	return re, &nfSet{set: set, elements: se}
+}
@@ -0,0 +1,245 @@
+package nftableslib
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Offsets of fields in ethernet header
+	l2OffsetDst       = 0
+	l2OffsetSrc       = 6
+	l2OffsetEtherType = 12
+	// Offsets of fields in 802.1Q header, which follows ethernet addresses
+	l2OffsetVLANTCI       = 14
+	l2OffsetVLANEtherType = 16
+)
+
+func createL2(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, error) {
+	re := []expr.Any{}
+	e := []expr.Any{}
+	sets := make([]*nfSet, 0)
+	var set *nfSet
+	var err error
+
+	switch family {
+	case nftables.TableFamilyBridge:
+	case nftables.TableFamilyNetdev:
+		if rule.L2.IBrName != "" || rule.L2.OBrName != "" {
+			return nil, nil, fmt.Errorf("bridge port names can be matched only in bridge table")
+		}
+	default:
+		return nil, nil, fmt.Errorf("L2 rule is not supported in %s table", familyName(family))
+	}
+	if err := rule.L2.Validate(); err != nil {
+		return nil, nil, err
+	}
+	if rule.L2.IBrName != "" {
+		re = append(re, getExprForBridgeName(expr.MetaKeyBRIIIFNAME, rule.L2.IBrName, rule.L2.RelOp)...)
+	}
+	if rule.L2.OBrName != "" {
+		re = append(re, getExprForBridgeName(expr.MetaKeyBRIOIFNAME, rule.L2.OBrName, rule.L2.RelOp)...)
+	}
+	if rule.L2.Src != nil {
+		if e, set, err = processMACAddr(rule.L2.Src, expr.PayloadBaseLLHeader, l2OffsetSrc); err != nil {
+			return nil, nil, err
+		}
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+	if rule.L2.Dst != nil {
+		if e, set, err = processMACAddr(rule.L2.Dst, expr.PayloadBaseLLHeader, l2OffsetDst); err != nil {
+			return nil, nil, err
+		}
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+	etherTypeOffset := uint32(l2OffsetEtherType)
+	if rule.L2.VLAN != nil {
+		re = append(re, getExprForVLAN(rule.L2.VLAN, rule.L2.RelOp)...)
+		etherTypeOffset = l2OffsetVLANEtherType
+	}
+	if rule.L2.EtherType != nil {
+		re = append(re, getExprForEtherType(etherTypeOffset, *rule.L2.EtherType, rule.L2.RelOp)...)
+	}
+
+	return re, sets, nil
+}
+
+func processMACAddr(addrs *MACAddrSpec, base expr.PayloadBase, offset uint32) ([]expr.Any, *nfSet, error) {
+	// [ payload load 6b @ link header + 6 => reg 1 ]
+	re := []expr.Any{&expr.Payload{
+		DestRegister: 1,
+		Base:         base,
+		Offset:       offset,
+		Len:          6,
+	}}
+	excl := false
+	if addrs.RelOp == NEQ {
+		excl = true
+	}
+	switch {
+	case addrs.SetRef != nil:
+		// [ lookup reg 1 set macs ]
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          addrs.SetRef.ID,
+			SetName:        addrs.SetRef.Name,
+		})
+		return re, nil, nil
+	case len(addrs.List) == 1:
+		// Special case when a single MAC is provided in the list
+		// [ cmp eq reg 1 0x00000000 0x00000000 ]
+		cmpOp := expr.CmpOpEq
+		if excl {
+			cmpOp = expr.CmpOpNeq
+		}
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte(addrs.List[0]),
+		})
+		return re, nil, nil
+	}
+	set := &nftables.Set{
+		Anonymous: false,
+		Constant:  true,
+		Name:      getSetName(),
+		ID:        uint32(rand.Intn(0xffff)),
+		KeyType:   nftables.TypeEtherAddr,
+	}
+	se := make([]nftables.SetElement, 0, len(addrs.List))
+	for _, addr := range addrs.List {
+		se = append(se, nftables.SetElement{Key: []byte(addr)})
+	}
+	re = append(re, &expr.Lookup{
+		SourceRegister: 1,
+		Invert:         excl,
+		SetID:          set.ID,
+		SetName:        set.Name,
+	})
+
+	return re, &nfSet{set: set, elements: se}, nil
+}
+
+func getExprForBridgeName(key expr.MetaKey, name string, op Operator) []expr.Any {
+	cmpOp := expr.CmpOpEq
+	if op == NEQ {
+		cmpOp = expr.CmpOpNeq
+	}
+	// [ meta load bri_iifname => reg 1 ]
+	// [ cmp eq reg 1 0x00306262 0x00000000 0x00000000 0x00000000 ]
+	return []expr.Any{
+		&expr.Meta{Key: key, Register: 1},
+		&expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     ifname(name),
+		},
+	}
+}
+
+func getExprForEtherType(offset uint32, etherType uint16, op Operator) []expr.Any {
+	cmpOp := expr.CmpOpEq
+	if op == NEQ {
+		cmpOp = expr.CmpOpNeq
+	}
+	// [ payload load 2b @ link header + 12 => reg 1 ]
+	// [ cmp eq reg 1 0x00000008 ]
+	return []expr.Any{
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseLLHeader,
+			Offset:       offset,
+			Len:          2,
+		},
+		&expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(etherType),
+		},
+	}
+}
+
+func getExprForVLAN(vlan *VLAN, op Operator) []expr.Any {
+	cmpOp := expr.CmpOpEq
+	if op == NEQ {
+		cmpOp = expr.CmpOpNeq
+	}
+	// Only tagged frames are matched
+	// [ payload load 2b @ link header + 12 => reg 1 ]
+	// [ cmp eq reg 1 0x00000081 ]
+	re := getExprForEtherType(l2OffsetEtherType, unix.ETH_P_8021Q, EQ)
+	if vlan.ID != nil {
+		// [ payload load 2b @ link header + 14 => reg 1 ]
+		// [ bitwise reg 1 = ( reg 1 & 0x0000ff0f ) ^ 0x00000000 ]
+		// [ cmp eq reg 1 0x00000a00 ]
+		re = append(re, &expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseLLHeader,
+			Offset:       l2OffsetVLANTCI,
+			Len:          2,
+		})
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            2,
+			Mask:           binaryutil.BigEndian.PutUint16(0x0fff),
+			Xor:            []byte{0x0, 0x0},
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     binaryutil.BigEndian.PutUint16(*vlan.ID),
+		})
+	}
+	if vlan.PCP != nil {
+		// [ payload load 1b @ link header + 14 => reg 1 ]
+		// [ bitwise reg 1 = ( reg 1 & 0x000000e0 ) ^ 0x00000000 ]
+		// [ cmp eq reg 1 0x000000a0 ]
+		re = append(re, &expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseLLHeader,
+			Offset:       l2OffsetVLANTCI,
+			Len:          1,
+		})
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            1,
+			Mask:           []byte{0xe0},
+			Xor:            []byte{0x0},
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{*vlan.PCP << 5},
+		})
+	}
+
+	return re
+}
+
+// NewMACAddr is a helper function which converts ethernet address into the format required by MACAddrSpec
+func NewMACAddr(addr string) (net.HardwareAddr, error) {
+	mac, err := net.ParseMAC(addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(mac) != 6 {
+		return nil, fmt.Errorf("%s is invalid ethernet address", addr)
+	}
+
+	return mac, nil
+}
@@ -5,16 +5,25 @@ import (
 	"math/rand"
 
 	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
 	"github.com/google/nftables/expr"
 )
 
-func createL3(l3proto nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, error) {
+func createL3(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, error) {
 	re := []expr.Any{}
 	e := []expr.Any{}
 	sets := make([]*nfSet, 0)
 	var set []*nfSet
 	var err error
 
+	l3proto, err := getL3Family(family, rule.L3)
+	if err != nil {
+		return nil, nil, err
+	}
+	// inet, netdev and bridge tables carry both ipv4 and ipv6 packets, the rule must be guarded by the family check,
+	// otherwise network header offsets of one family would be applied to packets of the other.
+	re = append(re, getExprForL3Guard(family, l3proto)...)
+
 	// Processing non-nil keys defined in L3 portion of a rule
 	if rule.L3.Version != nil {
 		if e, _, err = processVersion(*rule.L3.Version, rule.L3.RelOp); err != nil {
@@ -49,12 +58,159 @@ func createL3(l3proto nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, e
 		}
 		re = append(re, e...)
 	}
+
+	if rule.L3.ExtHdr != nil {
+		if l3proto != nftables.TableFamilyIPv6 {
+			return nil, nil, fmt.Errorf("extension headers can be matched only in ipv6 packets")
+		}
+		if err := rule.L3.ExtHdr.Validate(); err != nil {
+			return nil, nil, err
+		}
+		e, set := processIPv6ExtHdr(rule.L3.ExtHdr)
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+	if e, err = processIPHeaderFields(l3proto, rule.L3); err != nil {
+		return nil, nil, err
+	}
+	re = append(re, e...)
 	if rule.L3.Counter != nil {
 		re = append(re, getExprForCounter()...)
 	}
 	return re, sets, nil
 }
 
+// getL3Family returns the address family used to build L3 expressions of the rule. For ip and ip6 tables
+// it is the family of the table. For inet, netdev and bridge tables the family is derived from ip addresses, ip version
+// and family specific header fields carried by the rule, if none of them is specified, the table family is returned and only family
+// independent expressions can be used.
+func getL3Family(family nftables.TableFamily, l3 *L3Rule) (nftables.TableFamily, error) {
+	switch family {
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6:
+		return family, nil
+	case nftables.TableFamilyINet, nftables.TableFamilyNetdev, nftables.TableFamilyBridge:
+	default:
+		return 0, fmt.Errorf("unsupported table family %d", family)
+	}
+	l3proto := family
+	check := func(f nftables.TableFamily) error {
+		if l3proto != family && l3proto != f {
+			return fmt.Errorf("cannot mix ipv4 and ipv6 in the same rule")
+		}
+		l3proto = f
+		return nil
+	}
+	if l3.Version != nil {
+		switch *l3.Version {
+		case 4:
+			l3proto = nftables.TableFamilyIPv4
+		case 6:
+			l3proto = nftables.TableFamilyIPv6
+		default:
+			return 0, fmt.Errorf("invalid ip version %d", *l3.Version)
+		}
+	}
+	if l3.ExtHdr != nil || l3.FlowLabel != nil || l3.TrafficClass != nil {
+		if err := check(nftables.TableFamilyIPv6); err != nil {
+			return 0, err
+		}
+	}
+	if l3.ID != nil || l3.FragOffset != nil || l3.MoreFragments != nil {
+		if err := check(nftables.TableFamilyIPv4); err != nil {
+			return 0, err
+		}
+	}
+	for _, spec := range []*IPAddrSpec{l3.Src, l3.Dst} {
+		if spec == nil {
+			continue
+		}
+		addrs := append([]*IPAddr{}, spec.List...)
+		if spec.Range[0] != nil && spec.Range[1] != nil {
+			addrs = append(addrs, spec.Range[0], spec.Range[1])
+		}
+		for _, addr := range addrs {
+			f := nftables.TableFamilyIPv4
+			if addr.IsIPv6() {
+				f = nftables.TableFamilyIPv6
+			}
+			if err := check(f); err != nil {
+				return 0, err
+			}
+		}
+		if spec.SetRef != nil && l3proto == family {
+			return 0, fmt.Errorf("address family of set %s cannot be derived in %s table, ip Version must be specified",
+				spec.SetRef.Name, familyName(family))
+		}
+	}
+
+	return l3proto, nil
+}
+
+// getMatchL3Family returns the address family used by Dynamic and MatchAct rules. For inet, netdev and bridge tables,
+// when the rule matches L3 addresses, the family must be provided by the rule.
+func getMatchL3Family(family nftables.TableFamily, match MatchType, l3proto nftables.TableFamily) (nftables.TableFamily, error) {
+	switch family {
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6:
+		return family, nil
+	case nftables.TableFamilyINet, nftables.TableFamilyNetdev, nftables.TableFamilyBridge:
+	default:
+		return 0, fmt.Errorf("unsupported table family %d", family)
+	}
+	if match != MatchTypeL3Src && match != MatchTypeL3Dst {
+		return family, nil
+	}
+	if l3proto != nftables.TableFamilyIPv4 && l3proto != nftables.TableFamilyIPv6 {
+		return 0, fmt.Errorf("matching of addresses in %s table requires L3Proto to be either ipv4 or ipv6", familyName(family))
+	}
+
+	return l3proto, nil
+}
+
+// isMultiFamily returns true if a table of the family carries packets of both ipv4 and ipv6 address families.
+func isMultiFamily(family nftables.TableFamily) bool {
+	switch family {
+	case nftables.TableFamilyINet, nftables.TableFamilyNetdev, nftables.TableFamilyBridge:
+		return true
+	}
+
+	return false
+}
+
+// familyName returns the name of the table family as used by nft.
+func familyName(family nftables.TableFamily) string {
+	switch family {
+	case nftables.TableFamilyIPv4:
+		return "ip"
+	case nftables.TableFamilyIPv6:
+		return "ip6"
+	case nftables.TableFamilyINet:
+		return "inet"
+	case nftables.TableFamilyNetdev:
+		return "netdev"
+	case nftables.TableFamilyBridge:
+		return "bridge"
+	case nftables.TableFamilyARP:
+		return "arp"
+	}
+
+	return fmt.Sprintf("%d", family)
+}
+
+// l3Offsets returns offsets of source and destination addresses, the length of address
+// and offset of L4 protocol field in the network header of ipv4 or ipv6 packet.
+func l3Offsets(l3proto nftables.TableFamily) (uint32, uint32, uint32, uint32, error) {
+	switch l3proto {
+	case nftables.TableFamilyIPv4:
+		return 12, 16, 4, 9, nil
+	case nftables.TableFamilyIPv6:
+		return 8, 24, 16, 6, nil
+	}
+
+	return 0, 0, 0, 0, fmt.Errorf("unsupported table family %d", l3proto)
+}
+
 func processAddrList(l3proto nftables.TableFamily, offset uint32, list []*IPAddr,
 	op Operator) ([]expr.Any, *nfSet, error) {
 
@@ -119,27 +275,28 @@ func processProtocol(l3proto nftables.TableFamily, proto uint32, op Operator) ([
 }
 
 func processIPAddr(l3proto nftables.TableFamily, addrs *IPAddrSpec, src bool, op Operator) ([]expr.Any, []*nfSet, error) {
-	var addrOffset uint32
+	srcOffset, dstOffset, _, _, err := l3Offsets(l3proto)
+	if err != nil {
+		return nil, nil, err
+	}
+	addrOffset := dstOffset
+	if src {
+		addrOffset = srcOffset
+	}
+
+	return processIPAddrAtOffset(l3proto, addrs, addrOffset, op)
+}
+
+// processIPAddrAtOffset builds expressions to match addresses located at addrOffset of the network header.
+func processIPAddrAtOffset(l3proto nftables.TableFamily, addrs *IPAddrSpec, addrOffset uint32, op Operator) ([]expr.Any, []*nfSet, error) {
 	var keyType nftables.SetDatatype
 	var set *nfSet
 	var err error
 	sets := make([]*nfSet, 0)
 	e := []expr.Any{}
 	re := []expr.Any{}
-	switch l3proto {
-	case nftables.TableFamilyIPv4:
-		if src {
-			addrOffset = 12
-		} else {
-			addrOffset = 16
-		}
-		keyType = nftables.TypeIPAddr
-	case nftables.TableFamilyIPv6:
-		if src {
-			addrOffset = 8
-		} else {
-			addrOffset = 24
-		}
+	keyType = nftables.TypeIPAddr
+	if l3proto == nftables.TableFamilyIPv6 {
 		keyType = nftables.TypeIP6Addr
 	}
 	// There are three sources for addresses; List, Range and Set/Map/Vmap
@@ -166,3 +323,119 @@ func processIPAddr(l3proto nftables.TableFamily, addrs *IPAddrSpec, src bool, op
 
 	return re, sets, nil
 }
+
+// ipHeaderField describes the location of a field in the network header, when the field does not occupy
+// whole bytes, mask selects bits of the field and shift defines the position of the lowest bit.
+type ipHeaderField struct {
+	name   string
+	field  *HeaderField
+	offset uint32
+	len    uint32
+	mask   uint32
+	shift  uint
+	max    uint32
+}
+
+func getIPHeaderFields(l3proto nftables.TableFamily, l3 *L3Rule) ([]ipHeaderField, error) {
+	switch l3proto {
+	case nftables.TableFamilyIPv4:
+		if l3.FlowLabel != nil || l3.TrafficClass != nil {
+			return nil, fmt.Errorf("flow label and traffic class can be matched only in ipv6 packets")
+		}
+		return []ipHeaderField{
+			{"dscp", l3.DSCP, 1, 1, 0xfc, 2, 0x3f},
+			{"ecn", l3.ECN, 1, 1, 0x03, 0, 0x3},
+			{"length", l3.Length, 2, 2, 0, 0, 0xffff},
+			{"id", l3.ID, 4, 2, 0, 0, 0xffff},
+			{"fragment offset", l3.FragOffset, 6, 2, 0x1fff, 0, 0x1fff},
+			{"ttl", l3.TTL, 8, 1, 0, 0, 0xff},
+		}, nil
+	case nftables.TableFamilyIPv6:
+		if l3.ID != nil || l3.FragOffset != nil || l3.MoreFragments != nil {
+			return nil, fmt.Errorf("identification, fragment offset and flags can be matched only in ipv4 packets")
+		}
+		return []ipHeaderField{
+			{"dscp", l3.DSCP, 0, 2, 0x0fc0, 6, 0x3f},
+			{"ecn", l3.ECN, 1, 1, 0x30, 4, 0x3},
+			{"traffic class", l3.TrafficClass, 0, 2, 0x0ff0, 4, 0xff},
+			{"flow label", l3.FlowLabel, 1, 3, 0x0fffff, 0, 0xfffff},
+			{"length", l3.Length, 4, 2, 0, 0, 0xffff},
+			{"hop limit", l3.TTL, 7, 1, 0, 0, 0xff},
+		}, nil
+	}
+	if l3.DSCP != nil || l3.ECN != nil || l3.TTL != nil || l3.Length != nil {
+		return nil, fmt.Errorf("address family of ip header fields cannot be derived in %s table, ip Version must be specified",
+			familyName(l3proto))
+	}
+
+	return nil, nil
+}
+
+func processIPHeaderFields(l3proto nftables.TableFamily, l3 *L3Rule) ([]expr.Any, error) {
+	re := []expr.Any{}
+	fields, err := getIPHeaderFields(l3proto, l3)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		if f.field == nil {
+			continue
+		}
+		if f.field.Value > f.max {
+			return nil, fmt.Errorf("value %d of %s exceeds maximum value of %d", f.field.Value, f.name, f.max)
+		}
+		if err := validateRelOp(f.field.RelOp, true); err != nil {
+			return nil, err
+		}
+		re = append(re, getExprForIPHeaderField(f)...)
+	}
+	if l3.MoreFragments != nil {
+		if err := validateRelOp(l3.RelOp, false); err != nil {
+			return nil, err
+		}
+		mf := uint32(0)
+		if *l3.MoreFragments {
+			mf = 1
+		}
+		// MF flag is matched as a field of 1 bit following reserved and DF flags
+		re = append(re, getExprForIPHeaderField(ipHeaderField{
+			field:  &HeaderField{Value: mf, RelOp: l3.RelOp},
+			offset: 6,
+			len:    1,
+			mask:   0x20,
+			shift:  5,
+		})...)
+	}
+
+	return re, nil
+}
+
+func getExprForIPHeaderField(f ipHeaderField) []expr.Any {
+	// Values are stored in the network byte order, the field occupies the last len bytes of 4 bytes buffer
+	value := binaryutil.BigEndian.PutUint32(f.field.Value << f.shift)[4-f.len:]
+	// [ payload load 1b @ network header + 8 => reg 1 ]
+	re := []expr.Any{&expr.Payload{
+		DestRegister: 1,
+		Base:         expr.PayloadBaseNetworkHeader,
+		Offset:       f.offset,
+		Len:          f.len,
+	}}
+	if f.mask != 0 {
+		// [ bitwise reg 1 = ( reg 1 & 0x000000fc ) ^ 0x00000000 ]
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            f.len,
+			Mask:           binaryutil.BigEndian.PutUint32(f.mask)[4-f.len:],
+			Xor:            make([]byte, f.len),
+		})
+	}
+	// [ cmp lt reg 1 0x00000005 ]
+	re = append(re, &expr.Cmp{
+		Op:       getCmpOp(f.field.RelOp),
+		Register: 1,
+		Data:     value,
+	})
+
+	return re
+}
@@ -1,12 +1,14 @@
 package nftableslib
 
 import (
+	"fmt"
 	"math/rand"
 
 	"github.com/google/nftables/binaryutil"
 	"github.com/google/nftables/expr"
 
 	"github.com/google/nftables"
+	"golang.org/x/sys/unix"
 )
 
 func createL4(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, error) {
@@ -14,9 +16,30 @@ func createL4(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, er
 	sets := make([]*nfSet, 0)
 
 	l4 := rule.L4
+	if l4.ICMP != nil {
+		if err := l4.Validate(); err != nil {
+			return nil, nil, err
+		}
+		proto, err := getICMPProto(family, rule)
+		if err != nil {
+			return nil, nil, err
+		}
+		e, set := processICMP(proto, l4.ICMP)
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
+	proto := l4.L4Proto
+	if l4.TCPFlags != nil {
+		if err := l4.Validate(); err != nil {
+			return nil, nil, err
+		}
+		proto = unix.IPPROTO_TCP
+	}
 	if l4.Src != nil {
 		// 0 bytes is offset for Source ports in L4 header
-		e, set, err := processPort(l4.L4Proto, 0, l4.Src)
+		e, set, err := processPort(proto, 0, l4.Src)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -27,7 +50,7 @@ func createL4(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, er
 	}
 	if l4.Dst != nil {
 		// 2 bytes is offset for Source ports in L4 header
-		e, set, err := processPort(l4.L4Proto, 2, l4.Dst)
+		e, set, err := processPort(proto, 2, l4.Dst)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -36,6 +59,13 @@ func createL4(family nftables.TableFamily, rule *Rule) ([]expr.Any, []*nfSet, er
 		}
 		re = append(re, e...)
 	}
+	if l4.TCPFlags != nil {
+		e, set := processTCPFlags(l4.TCPFlags)
+		if set != nil {
+			sets = append(sets, set)
+		}
+		re = append(re, e...)
+	}
 	if rule.L4.Counter != nil {
 		re = append(re, getExprForCounter()...)
 	}
@@ -114,3 +144,196 @@ func processPortRange(l4proto uint8, offset uint32, port [2]*uint16, op Operator
 	}
 	return re, nil, nil
 }
+
+// getICMPProto returns ICMP protocol matching the family of the table, for tables carrying both ipv4 and ipv6
+// packets, the protocol is derived from the address family of the rule.
+func getICMPProto(family nftables.TableFamily, rule *Rule) (uint8, error) {
+	l3proto := family
+	if isMultiFamily(family) && rule.L3 != nil {
+		var err error
+		if l3proto, err = getL3Family(family, rule.L3); err != nil {
+			return 0, err
+		}
+	}
+	var proto uint8
+	switch l3proto {
+	case nftables.TableFamilyIPv4:
+		proto = unix.IPPROTO_ICMP
+	case nftables.TableFamilyIPv6:
+		proto = unix.IPPROTO_ICMPV6
+	}
+	switch {
+	case rule.L4.L4Proto == 0 && proto == 0:
+		return 0, fmt.Errorf("icmp protocol cannot be derived in %s table, L4Proto must be specified", familyName(family))
+	case rule.L4.L4Proto == 0:
+		return proto, nil
+	case proto != 0 && rule.L4.L4Proto != proto:
+		return 0, fmt.Errorf("L4Proto %d does not match the address family of the rule", rule.L4.L4Proto)
+	}
+
+	return rule.L4.L4Proto, nil
+}
+
+func processICMP(proto uint8, icmp *ICMP) ([]expr.Any, *nfSet) {
+	// [ meta load l4proto => reg 1 ]
+	// [ cmp eq reg 1 0x00000001 ]
+	re := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     []byte{proto},
+		},
+	}
+	var nfset *nfSet
+	cmpOp := expr.CmpOpEq
+	excl := false
+	if icmp.RelOp == NEQ {
+		cmpOp = expr.CmpOpNeq
+		excl = true
+	}
+	if len(icmp.Type) != 0 || icmp.SetRef != nil {
+		// [ payload load 1b @ transport header + 0 => reg 1 ]
+		re = append(re, &expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseTransportHeader,
+			Offset:       0,
+			Len:          1,
+		})
+	}
+	switch {
+	case icmp.SetRef != nil:
+		// [ lookup reg 1 set icmp_types ]
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          icmp.SetRef.ID,
+			SetName:        icmp.SetRef.Name,
+		})
+	case len(icmp.Type) == 1:
+		// [ cmp eq reg 1 0x00000008 ]
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{icmp.Type[0]},
+		})
+	case len(icmp.Type) > 1:
+		keyType := nftables.TypeICMPType
+		if proto == unix.IPPROTO_ICMPV6 {
+			keyType = nftables.TypeICMP6Type
+		}
+		set := &nftables.Set{
+			Anonymous: false,
+			Constant:  true,
+			Name:      getSetName(),
+			ID:        uint32(rand.Intn(0xffff)),
+			KeyType:   keyType,
+		}
+		se := make([]nftables.SetElement, len(icmp.Type))
+		for i, t := range icmp.Type {
+			se[i].Key = []byte{t}
+		}
+		nfset = &nfSet{set: set, elements: se}
+		// [ lookup reg 1 set __set%d ]
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          set.ID,
+			SetName:        set.Name,
+		})
+	}
+	if icmp.Code != nil {
+		// [ payload load 1b @ transport header + 1 => reg 1 ]
+		// [ cmp eq reg 1 0x00000003 ]
+		re = append(re, &expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseTransportHeader,
+			Offset:       1,
+			Len:          1,
+		})
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{*icmp.Code},
+		})
+	}
+
+	return re, nfset
+}
+
+func processTCPFlags(flags *TCPFlags) ([]expr.Any, *nfSet) {
+	// [ meta load l4proto => reg 1 ]
+	// [ cmp eq reg 1 0x00000006 ]
+	// [ payload load 1b @ transport header + 13 => reg 1 ]
+	re := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+		&expr.Cmp{
+			Op:       expr.CmpOpEq,
+			Register: 1,
+			Data:     []byte{unix.IPPROTO_TCP},
+		},
+		&expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseTransportHeader,
+			Offset:       13, // Offset for flags in TCP header
+			Len:          1,
+		},
+	}
+	if flags.Mask != 0 {
+		// [ bitwise reg 1 = ( reg 1 & 0x00000012 ) ^ 0x00000000 ]
+		re = append(re, &expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            1,
+			Mask:           []byte{flags.Mask},
+			Xor:            []byte{0x0},
+		})
+	}
+	excl := false
+	if flags.RelOp == NEQ {
+		excl = true
+	}
+	switch {
+	case flags.SetRef != nil:
+		// [ lookup reg 1 set flags ]
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          flags.SetRef.ID,
+			SetName:        flags.SetRef.Name,
+		})
+	case len(flags.List) != 0:
+		set := &nftables.Set{
+			Anonymous: false,
+			Constant:  true,
+			Name:      getSetName(),
+			ID:        uint32(rand.Intn(0xffff)),
+			KeyType:   nftables.TypeTCPFlag,
+		}
+		se := make([]nftables.SetElement, len(flags.List))
+		for i, f := range flags.List {
+			se[i].Key = []byte{f}
+		}
+		// [ lookup reg 1 set __set%d ]
+		re = append(re, &expr.Lookup{
+			SourceRegister: 1,
+			Invert:         excl,
+			SetID:          set.ID,
+			SetName:        set.Name,
+		})
+		return re, &nfSet{set: set, elements: se}
+	default:
+		// [ cmp eq reg 1 0x00000002 ]
+		cmpOp := expr.CmpOpEq
+		if excl {
+			cmpOp = expr.CmpOpNeq
+		}
+		re = append(re, &expr.Cmp{
+			Op:       cmpOp,
+			Register: 1,
+			Data:     []byte{flags.Value},
+		})
+	}
+
+	return re, nil
+}
@@ -8,7 +8,7 @@ import (
 	"github.com/google/nftables/expr"
 )
 
-func getExprForMatchAct(nfr *nfRules, l3proto nftables.TableFamily, matchAct *MatchAct) ([]expr.Any, error) {
+func getExprForMatchAct(nfr *nfRules, family nftables.TableFamily, matchAct *MatchAct) ([]expr.Any, error) {
 	if matchAct == nil {
 		return nil, fmt.Errorf("MatchAct is nil")
 	}
@@ -31,25 +31,21 @@ func getExprForMatchAct(nfr *nfRules, l3proto nftables.TableFamily, matchAct *Ma
 		})
 	}
 
-	var l3OffsetSrc, l3OffsetDst, l3AddrLen /*, l4ProtoOffset*/ uint32
+	var l3OffsetSrc, l3OffsetDst, l3AddrLen uint32
 	l4OffsetSrc := uint32(0)
 	l4OffsetDst := uint32(2)
 	re := []expr.Any{}
 
-	switch l3proto {
-	case nftables.TableFamilyIPv4:
-		l3OffsetSrc = 12
-		l3OffsetDst = 16
-		l3AddrLen = 4
-		// l4ProtoOffset = 9
-	case nftables.TableFamilyIPv6:
-		l3OffsetSrc = 8
-		l3OffsetDst = 24
-		l3AddrLen = 16
-		// l4ProtoOffset = 6
-	default:
-		return nil, fmt.Errorf("unsupported table family %d", l3proto)
+	l3proto, err := getMatchL3Family(family, matchAct.Match, matchAct.L3Proto)
+	if err != nil {
+		return nil, err
+	}
+	if !isMultiFamily(l3proto) {
+		if l3OffsetSrc, l3OffsetDst, l3AddrLen, _, err = l3Offsets(l3proto); err != nil {
+			return nil, err
+		}
 	}
+	re = append(re, getExprForL3Guard(family, l3proto)...)
 
 	switch matchAct.Match {
 	case MatchTypeL3Src:
@@ -0,0 +1,108 @@
+package nftableslib
+
+import (
+	"fmt"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// MetaSet defines a statement setting meta Key of packet, Key is one of unix.NFT_META_PRIORITY, unix.NFT_META_NFTRACE,
+// unix.NFT_META_PKTTYPE or unix.NFT_META_SECMARK. The key is set either to Value or to the value looked up in Map.
+// Priority carries 4 bytes in the host byte order, example the tc class returned by TCHandle, nftrace and pkttype carry
+// a single byte, pkttype is one of unix.PACKET_* types. Secmark is set by the reference to the secmark object of
+// the table named Object, google/nftables does not support maps of objects, secmark cannot be looked up in a map.
+type MetaSet struct {
+	Key    uint32
+	Value  []byte
+	Map    *MetaSetMap
+	Object string
+}
+
+// MetaSetMap defines a map selecting the value of meta key by the concatenation of packet's fields defined by Key,
+// example ip daddr . tcp dport, the data of the map must be of the length of the value of meta key.
+type MetaSetMap struct {
+	Key    []*ConcatElement
+	SetRef *SetRef
+}
+
+// TCHandle returns the value of meta priority selecting tc class major:minor, example TCHandle(1, 0x10) is 1:10
+func TCHandle(major, minor uint16) []byte {
+	return binaryutil.NativeEndian.PutUint32(uint32(major)<<16 | uint32(minor))
+}
+
+// Validate checks parameters of MetaSet struct
+func (m *MetaSet) Validate() error {
+	length := 1
+	switch m.Key {
+	case unix.NFT_META_PRIORITY:
+		length = 4
+	case unix.NFT_META_NFTRACE, unix.NFT_META_PKTTYPE:
+	case unix.NFT_META_SECMARK:
+		if m.Object == "" || m.Value != nil || m.Map != nil {
+			return fmt.Errorf("secmark can only be set by the reference to secmark object")
+		}
+		return nil
+	default:
+		return fmt.Errorf("meta key %d cannot be set", m.Key)
+	}
+	if m.Object != "" {
+		return fmt.Errorf("only secmark can be set by the reference to an object")
+	}
+	if (m.Value == nil) == (m.Map == nil) {
+		return fmt.Errorf("either value or map must be specified to set meta key %d", m.Key)
+	}
+	if m.Map != nil {
+		if m.Map.SetRef == nil {
+			return fmt.Errorf("map setting meta key %d requires reference to the map", m.Key)
+		}
+		if len(m.Map.Key) == 0 {
+			return fmt.Errorf("map setting meta key %d requires key", m.Key)
+		}
+		return nil
+	}
+	if len(m.Value) != length {
+		return fmt.Errorf("invalid length %d of value of meta key %d, expected %d", len(m.Value), m.Key, length)
+	}
+
+	return nil
+}
+
+func getExprForMetaSet(family nftables.TableFamily, sets []MetaSet) ([]expr.Any, error) {
+	re := []expr.Any{}
+	for _, m := range sets {
+		if err := m.Validate(); err != nil {
+			return nil, err
+		}
+		switch {
+		case m.Object != "":
+			// [ objref type 8 name sshtag ]
+			re = append(re, &expr.Objref{Type: unix.NFT_OBJECT_SECMARK, Name: m.Object})
+			continue
+		case m.Map != nil:
+			// [ payload load 4b @ network header + 16 => reg 1 ]
+			// [ lookup reg 1 set __map%d dreg 1 ]
+			e, err := getExprForConcat(family, &Concat{Elements: m.Map.Key})
+			if err != nil {
+				return nil, err
+			}
+			re = append(re, e...)
+			re = append(re, &expr.Lookup{
+				SourceRegister: 1,
+				DestRegister:   1,
+				IsDestRegSet:   true,
+				SetID:          m.Map.SetRef.ID,
+				SetName:        m.Map.SetRef.Name,
+			})
+		default:
+			// [ immediate reg 1 0x00010010 ]
+			re = append(re, &expr.Immediate{Register: 1, Data: m.Value})
+		}
+		// [ meta set priority with reg 1 ]
+		re = append(re, &expr.Meta{Key: expr.MetaKey(m.Key), Register: 1, SourceRegister: true})
+	}
+
+	return re, nil
+}
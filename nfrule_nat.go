@@ -0,0 +1,199 @@
+package nftableslib
+
+import (
+	"fmt"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// NATMap defines a map selecting the address, or the address and the port, of nat by a key. The key is
+// the concatenation of packet's fields defined by Key, example ip saddr or ip daddr . tcp dport. When Modulus
+// is not 0, the key is the hash of Key's fields modulo Modulus or, if Key is empty, a random number from 0 to
+// Modulus-1, the map must be keyed by nftables.TypeInteger, example to spread connections among addresses
+// of a pool. DataType defines the data of the map, either nftables.TypeIPAddr or nftables.TypeIP6Addr,
+// or the concatenation of the address type and nftables.TypeInetService.
+type NATMap struct {
+	Key      []*ConcatElement
+	Modulus  uint32
+	SetRef   *SetRef
+	DataType nftables.SetDatatype
+}
+
+// Validate checks parameters of NATMap struct
+func (m *NATMap) Validate() error {
+	if m.SetRef == nil {
+		return fmt.Errorf("nat map requires reference to the map")
+	}
+	if len(m.Key) == 0 && m.Modulus == 0 {
+		return fmt.Errorf("nat map requires either key or modulus")
+	}
+	if _, _, err := natMapData(m.DataType); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// natMapData returns the family of addresses carried by data of nat map and true if the data carries port
+func natMapData(dataType nftables.SetDatatype) (nftables.TableFamily, bool, error) {
+	types := nftables.ConcatSetTypeElements(dataType)
+	if len(types) == 2 && types[1].Name != nftables.TypeInetService.Name {
+		return 0, false, fmt.Errorf("second element of data of nat map must be %s", nftables.TypeInetService.Name)
+	}
+	if len(types) == 0 || len(types) > 2 {
+		return 0, false, fmt.Errorf("invalid data type %s of nat map", dataType.Name)
+	}
+	switch types[0].Name {
+	case nftables.TypeIPAddr.Name:
+		return nftables.TableFamilyIPv4, len(types) == 2, nil
+	case nftables.TypeIP6Addr.Name:
+		return nftables.TableFamilyIPv6, len(types) == 2, nil
+	}
+
+	return 0, false, fmt.Errorf("invalid data type %s of nat map", dataType.Name)
+}
+
+// concatKeyLen returns the length of the key built from concatenation of elements, each element is padded
+// to the size of the register.
+func concatKeyLen(elements []*ConcatElement) uint32 {
+	l := uint32(0)
+	for _, e := range elements {
+		l += (e.EType.Bytes + 3) &^ 3
+	}
+
+	return l
+}
+
+// getExprForNATPool creates an anonymous map of pool's addresses and returns the map translating a random
+// number to one of them
+func getExprForNATPool(nfr *nfRules, pool []*IPAddr) (*NATMap, error) {
+	dataType := nftables.TypeIPAddr
+	if pool[0].IsIPv6() {
+		dataType = nftables.TypeIP6Addr
+	}
+	set := &nftables.Set{
+		Table:     nfr.table,
+		Anonymous: true,
+		Constant:  true,
+		IsMap:     true,
+		KeyType:   nftables.TypeInteger,
+		DataType:  dataType,
+	}
+	elements := make([]nftables.SetElement, len(pool))
+	for i, addr := range pool {
+		if addr.IsIPv6() != pool[0].IsIPv6() {
+			return nil, fmt.Errorf("cannot mix ipv4 and ipv6 addresses in nat pool")
+		}
+		elements[i].Key = binaryutil.NativeEndian.PutUint32(uint32(i))
+		if addr.IsIPv6() {
+			elements[i].Val = addr.IP.To16()
+		} else {
+			elements[i].Val = addr.IP.To4()
+		}
+	}
+	if err := nfr.conn.AddSet(set, elements); err != nil {
+		return nil, err
+	}
+
+	return &NATMap{
+		Modulus:  uint32(len(pool)),
+		SetRef:   &SetRef{Name: set.Name, ID: set.ID, IsMap: true},
+		DataType: dataType,
+	}, nil
+}
+
+func getExprForNATMap(nfr *nfRules, nat *nat) ([]expr.Any, error) {
+	family := nfr.table.Family
+	switch family {
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6, nftables.TableFamilyINet:
+	default:
+		return nil, fmt.Errorf("nat is not supported in %s table", familyName(family))
+	}
+	m := nat.natMap
+	if nat.pool != nil {
+		var err error
+		if m, err = getExprForNATPool(nfr, nat.pool); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	l3proto, port, _ := natMapData(m.DataType)
+	if family != nftables.TableFamilyINet && family != l3proto {
+		return nil, fmt.Errorf("data of nat map does not match %s table", familyName(family))
+	}
+	re := []expr.Any{}
+	if len(m.Key) != 0 {
+		keyFamily, err := getConcatL3Family(family, &Concat{Elements: m.Key})
+		if err != nil {
+			return nil, err
+		}
+		if !isMultiFamily(keyFamily) && keyFamily != l3proto {
+			return nil, fmt.Errorf("address family of key of nat map does not match its data")
+		}
+		if isMultiFamily(keyFamily) {
+			re = append(re, getExprForL3Guard(family, l3proto)...)
+		}
+		// [ payload load 4b @ network header + 12 => reg 1 ]
+		e, err := getExprForConcat(family, &Concat{Elements: m.Key})
+		if err != nil {
+			return nil, err
+		}
+		re = append(re, e...)
+		if m.Modulus != 0 {
+			// [ hash reg 1 = jhash(reg 1, 4, 0x0) % mod 2 ]
+			re = append(re, &expr.Hash{
+				SourceRegister: 1,
+				DestRegister:   1,
+				Length:         concatKeyLen(m.Key),
+				Modulus:        m.Modulus,
+				Type:           expr.HashTypeJenkins,
+			})
+		}
+	} else {
+		re = append(re, getExprForL3Guard(family, l3proto)...)
+		// [ numgen reg 1 = random mod 2 ]
+		re = append(re, &expr.Numgen{
+			Register: 1,
+			Modulus:  m.Modulus,
+			Type:     unix.NFT_NG_RANDOM,
+		})
+	}
+	// [ lookup reg 1 set __map%d dreg 1 ]
+	re = append(re, &expr.Lookup{
+		SourceRegister: 1,
+		DestRegister:   1,
+		IsDestRegSet:   true,
+		SetID:          m.SetRef.ID,
+		SetName:        m.SetRef.Name,
+	})
+	// [ nat dnat ip addr_min reg 1 proto_min reg 9 ]
+	e := &expr.NAT{
+		Type:       nat.nattype,
+		Family:     uint32(l3proto),
+		RegAddrMin: 1,
+	}
+	if port {
+		// The port follows the address in 4 bytes registers, reg 9 follows ipv4 and reg 12 ipv6 address
+		e.RegProtoMin = 9
+		if l3proto == nftables.TableFamilyIPv6 {
+			e.RegProtoMin = 12
+		}
+	}
+	if nat.random != nil {
+		e.Random = *nat.random
+	}
+	if nat.fullyRandom != nil {
+		e.FullyRandom = *nat.fullyRandom
+	}
+	if nat.persistent != nil {
+		e.Persistent = *nat.persistent
+	}
+	re = append(re, e)
+
+	return re, nil
+}
@@ -0,0 +1,139 @@
+package nftableslib
+
+import (
+	"fmt"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// ICMPRejectCode defines the code of icmp destination unreachable message sent by reject action
+type ICMPRejectCode uint8
+
+// Codes of icmp destination unreachable message
+const (
+	ICMPRejectNetUnreachable  ICMPRejectCode = 0
+	ICMPRejectHostUnreachable ICMPRejectCode = 1
+	ICMPRejectProtUnreachable ICMPRejectCode = 2
+	ICMPRejectPortUnreachable ICMPRejectCode = 3
+	ICMPRejectFragNeeded      ICMPRejectCode = 4
+	ICMPRejectNetProhibited   ICMPRejectCode = 9
+	ICMPRejectHostProhibited  ICMPRejectCode = 10
+	ICMPRejectAdminProhibited ICMPRejectCode = 13
+)
+
+// ICMPv6RejectCode defines the code of icmpv6 destination unreachable message sent by reject action
+type ICMPv6RejectCode uint8
+
+// Codes of icmpv6 destination unreachable message
+const (
+	ICMPv6RejectNoRoute         ICMPv6RejectCode = 0
+	ICMPv6RejectAdminProhibited ICMPv6RejectCode = 1
+	ICMPv6RejectAddrUnreachable ICMPv6RejectCode = 3
+	ICMPv6RejectPortUnreachable ICMPv6RejectCode = 4
+	ICMPv6RejectPolicyFail      ICMPv6RejectCode = 5
+	ICMPv6RejectRejectRoute     ICMPv6RejectCode = 6
+	icmpv6RejectCodeMax                          = ICMPv6RejectRejectRoute
+	icmpRejectCodeMax                            = 15
+)
+
+// ICMPxRejectCode defines the code of reject action common for icmp and icmpv6
+type ICMPxRejectCode uint8
+
+// Codes of reject action common for icmp and icmpv6
+const (
+	ICMPxRejectNoRoute         ICMPxRejectCode = unix.NFT_REJECT_ICMPX_NO_ROUTE
+	ICMPxRejectPortUnreachable ICMPxRejectCode = unix.NFT_REJECT_ICMPX_PORT_UNREACH
+	ICMPxRejectHostUnreachable ICMPxRejectCode = unix.NFT_REJECT_ICMPX_HOST_UNREACH
+	ICMPxRejectAdminProhibited ICMPxRejectCode = unix.NFT_REJECT_ICMPX_ADMIN_PROHIBITED
+)
+
+// icmpxToICMP maps icmpx codes to codes of icmp and icmpv6 used in ip and ip6 tables
+var icmpxToICMP = map[nftables.TableFamily][]uint8{
+	nftables.TableFamilyIPv4: {
+		ICMPxRejectNoRoute:         uint8(ICMPRejectNetUnreachable),
+		ICMPxRejectPortUnreachable: uint8(ICMPRejectPortUnreachable),
+		ICMPxRejectHostUnreachable: uint8(ICMPRejectHostUnreachable),
+		ICMPxRejectAdminProhibited: uint8(ICMPRejectAdminProhibited),
+	},
+	nftables.TableFamilyIPv6: {
+		ICMPxRejectNoRoute:         uint8(ICMPv6RejectNoRoute),
+		ICMPxRejectPortUnreachable: uint8(ICMPv6RejectPortUnreachable),
+		ICMPxRejectHostUnreachable: uint8(ICMPv6RejectAddrUnreachable),
+		ICMPxRejectAdminProhibited: uint8(ICMPv6RejectAdminProhibited),
+	},
+}
+
+// matchesTCP returns true if the rule matches only tcp packets
+func matchesTCP(rule *Rule) bool {
+	if rule.TCPOption != nil {
+		return true
+	}
+	if l4 := rule.L4; l4 != nil {
+		if l4.TCPFlags != nil {
+			return true
+		}
+		if l4.L4Proto == unix.IPPROTO_TCP && (l4.Src != nil || l4.Dst != nil) {
+			return true
+		}
+	}
+	if l3 := rule.L3; l3 != nil && l3.Protocol != nil {
+		return *l3.Protocol == unix.IPPROTO_TCP && l3.RelOp == EQ
+	}
+
+	return false
+}
+
+func getExprForReject(family nftables.TableFamily, rule *Rule) ([]expr.Any, error) {
+	r := rule.Action.reject
+	if r.typed {
+		if err := validateReject(family, rule); err != nil {
+			return nil, err
+		}
+	}
+	code := r.rejectCode
+	rejectType := r.rejectType
+	if codes, ok := icmpxToICMP[family]; ok && rejectType == unix.NFT_REJECT_ICMPX_UNREACH && int(code) < len(codes) {
+		// Only inet, bridge and netdev tables support icmpx, in ip and ip6 tables the code is translated
+		rejectType = unix.NFT_REJECT_ICMP_UNREACH
+		code = codes[code]
+	}
+	// [ reject type 0 code 3 ]
+	return []expr.Any{&expr.Reject{Type: rejectType, Code: code}}, nil
+}
+
+// validateReject checks that reject built by a typed constructor fits the table family and the rule
+func validateReject(family nftables.TableFamily, rule *Rule) error {
+	r := rule.Action.reject
+	if family == nftables.TableFamilyARP {
+		return fmt.Errorf("reject is not supported in %s table", familyName(family))
+	}
+	switch r.rejectType {
+	case unix.NFT_REJECT_TCP_RST:
+		if !matchesTCP(rule) {
+			return fmt.Errorf("tcp reset reject requires the rule to match tcp protocol")
+		}
+	case unix.NFT_REJECT_ICMP_UNREACH:
+		switch {
+		case family == nftables.TableFamilyIPv4 && r.l3proto == unix.NFPROTO_IPV6:
+			return fmt.Errorf("icmpv6 reject cannot be used in %s table", familyName(family))
+		case family == nftables.TableFamilyIPv6 && r.l3proto == unix.NFPROTO_IPV4:
+			return fmt.Errorf("icmp reject cannot be used in %s table", familyName(family))
+		}
+	}
+
+	return nil
+}
+
+// getExprForRejectGuard returns expressions restricting the rule to packets of the protocol of the code of reject
+// built by SetRejectICMP or SetRejectICMPv6, expressions are returned only for inet, bridge and netdev tables.
+func getExprForRejectGuard(family nftables.TableFamily, r *reject) []expr.Any {
+	if !r.typed || r.rejectType != unix.NFT_REJECT_ICMP_UNREACH {
+		return nil
+	}
+	// The code is sent in icmp and icmpv6 messages, the rule must match only packets of the code's protocol
+	// [ meta load nfproto => reg 1 ]
+	// [ cmp eq reg 1 0x00000002 ]
+	return getExprForL3Guard(family, nftables.TableFamily(r.l3proto))
+}
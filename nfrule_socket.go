@@ -0,0 +1,150 @@
+package nftableslib
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"golang.org/x/sys/unix"
+)
+
+// cgroupv2Root is the mount point of cgroup v2 hierarchy, paths of SocketCgroup are relative to it
+var cgroupv2Root = "/sys/fs/cgroup"
+
+// SocketCgroup defines cgroup v2 of the socket, the socket matches when it belongs to the cgroup or to any cgroup
+// below it. Path is relative to the root of cgroup v2 hierarchy, example "system.slice/nginx.service", the cgroup
+// is resolved to its id when the rule is built. Level is the depth of the cgroup in the hierarchy, when 0 it is
+// the number of elements of Path. When ID is not 0, it is used instead of resolving Path, example when the hierarchy
+// is not mounted in the namespace of the caller, Level must then be specified.
+type SocketCgroup struct {
+	Path  string
+	Level uint32
+	ID    uint64
+}
+
+// Validate checks parameters of SocketCgroup struct
+func (c *SocketCgroup) Validate() error {
+	if c.Path == "" && c.ID == 0 {
+		return fmt.Errorf("cgroupv2 requires either path or id")
+	}
+	if c.Path == "" && c.Level == 0 {
+		return fmt.Errorf("cgroupv2 specified by id requires level")
+	}
+	if c.Path != "" && c.Level == 0 && cgroupLevel(c.Path) == 0 {
+		return fmt.Errorf("cgroupv2 path %s cannot be the root of hierarchy", c.Path)
+	}
+
+	return nil
+}
+
+// cgroupLevel returns the depth of the cgroup path in the hierarchy, the root is at level 0
+func cgroupLevel(path string) uint32 {
+	path = strings.Trim(filepath.Clean("/"+path), "/")
+	if path == "" {
+		return 0
+	}
+
+	return uint32(strings.Count(path, "/") + 1)
+}
+
+// cgroupID returns the id of cgroup v2, the kernel identifies cgroups by inode numbers of their directories
+func cgroupID(path string) (uint64, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(filepath.Join(cgroupv2Root, path), &st); err != nil {
+		return 0, fmt.Errorf("failed to resolve cgroupv2 %s with error: %+v", path, err)
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		return 0, fmt.Errorf("cgroupv2 %s is not a directory", path)
+	}
+
+	return st.Ino, nil
+}
+
+// Socket defines matching of packets by the local socket they belong to, packets without a socket do not match.
+// Transparent matches sockets with or without IP_TRANSPARENT option, Wildcard matches sockets bound to the wildcard
+// address or to a specific one, Mark matches the mark of the socket set by SO_MARK and Cgroupv2 matches the cgroup
+// of the socket. All specified keys must match.
+type Socket struct {
+	Transparent *bool
+	Wildcard    *bool
+	Mark        *HeaderField
+	Cgroupv2    *SocketCgroup
+}
+
+// Validate checks parameters of Socket struct
+func (s *Socket) Validate() error {
+	if s.Transparent == nil && s.Wildcard == nil && s.Mark == nil && s.Cgroupv2 == nil {
+		return fmt.Errorf("socket requires at least one key to match")
+	}
+	if s.Mark != nil {
+		if err := validateRelOp(s.Mark.RelOp, true); err != nil {
+			return err
+		}
+	}
+	if s.Cgroupv2 != nil {
+		if err := s.Cgroupv2.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getExprForSocket(family nftables.TableFamily, s *Socket) ([]expr.Any, error) {
+	switch family {
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6, nftables.TableFamilyINet:
+	default:
+		return nil, fmt.Errorf("socket is not supported in %s table", familyName(family))
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	re := []expr.Any{}
+	for _, flag := range []struct {
+		key   expr.SocketKey
+		value *bool
+	}{
+		{key: expr.SocketKeyTransparent, value: s.Transparent},
+		{key: expr.SocketKeyWildcard, value: s.Wildcard},
+	} {
+		if flag.value == nil {
+			continue
+		}
+		data := []byte{0}
+		if *flag.value {
+			data[0] = 1
+		}
+		// [ socket load transparent => reg 1 ]
+		// [ cmp eq reg 1 0x00000001 ]
+		re = append(re, &expr.Socket{Key: flag.key, Register: 1})
+		re = append(re, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: data})
+	}
+	if s.Mark != nil {
+		// [ socket load mark => reg 1 ]
+		// [ cmp eq reg 1 0x00000001 ]
+		re = append(re, &expr.Socket{Key: expr.SocketKeyMark, Register: 1})
+		re = append(re, getExprForHostOrderCmp(s.Mark.RelOp, binaryutil.NativeEndian.PutUint32(s.Mark.Value))...)
+	}
+	if s.Cgroupv2 != nil {
+		level := s.Cgroupv2.Level
+		if level == 0 {
+			level = cgroupLevel(s.Cgroupv2.Path)
+		}
+		id := s.Cgroupv2.ID
+		if id == 0 {
+			var err error
+			if id, err = cgroupID(s.Cgroupv2.Path); err != nil {
+				return nil, err
+			}
+		}
+		// [ socket load cgroupv2 => reg 1 , level 2 ]
+		// [ cmp eq reg 1 0x00001f41 0x00000000 ]
+		re = append(re, &expr.Socket{Key: expr.SocketKeyCgroupv2, Level: level, Register: 1})
+		re = append(re, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: binaryutil.NativeEndian.PutUint64(id)})
+	}
+
+	return re, nil
+}
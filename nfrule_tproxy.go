@@ -0,0 +1,137 @@
+package nftableslib
+
+import (
+	"fmt"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+)
+
+// TProxyAttributes defines parameters of transparent proxy action, packets are delivered to the local socket
+// listening on Addr and Port without changing packet's headers. When Addr is nil, the destination address of packet
+// is used. Port must be specified, google/nftables always programs the register of the port. In inet tables, ipv4
+// address applies only to ipv4 packets and ipv6 address only to ipv6 packets, when Addr is nil both are delivered to Port.
+// Mark, when specified, sets the mark of packets delivered to the proxy, Mark.Set is implied, the mark is used by
+// policy routing to route packets to the local host. When Transparent is true, the rule matches only packets
+// of an existing socket with IP_TRANSPARENT option, example a connection already accepted by the proxy.
+type TProxyAttributes struct {
+	Addr        *IPAddr
+	Port        uint16
+	Mark        *MetaMark
+	Transparent bool
+}
+
+// tproxy defines transparent proxy action delivering packets to the socket listening on addr and port
+type tproxy struct {
+	addr        *IPAddr
+	port        uint16
+	mark        *MetaMark
+	transparent bool
+}
+
+// SetTProxy builds RuleAction struct for transparent proxy action
+func SetTProxy(attrs *TProxyAttributes) (*RuleAction, error) {
+	if attrs == nil || attrs.Port == 0 {
+		return nil, fmt.Errorf("port of tproxy must be specified")
+	}
+	if attrs.Addr != nil {
+		if err := validateHostAddr(attrs.Addr); err != nil {
+			return nil, err
+		}
+	}
+	tp := &tproxy{
+		addr:        attrs.Addr,
+		port:        attrs.Port,
+		transparent: attrs.Transparent,
+	}
+	if attrs.Mark != nil {
+		mark := *attrs.Mark
+		mark.Set = true
+		tp.mark = &mark
+	}
+
+	return &RuleAction{tproxy: tp}, nil
+}
+
+// validateHostAddr checks that addr is a valid address of a single host
+func validateHostAddr(addr *IPAddr) error {
+	if err := addr.Validate(); err != nil {
+		return err
+	}
+	full := uint8(32)
+	if addr.IsIPv6() {
+		full = 128
+	}
+	if addr.CIDR && addr.Mask != nil && *addr.Mask != full {
+		return fmt.Errorf("address %s/%d must be a host address", addr.IP.String(), *addr.Mask)
+	}
+
+	return nil
+}
+
+func getExprForTProxy(family nftables.TableFamily, tp *tproxy) ([]expr.Any, error) {
+	switch family {
+	case nftables.TableFamilyIPv4, nftables.TableFamilyIPv6, nftables.TableFamilyINet:
+	default:
+		return nil, fmt.Errorf("tproxy is not supported in %s table", familyName(family))
+	}
+	re := []expr.Any{}
+	// When only port is specified, inet table requires unspecified tproxy family
+	l3proto := family
+	if family == nftables.TableFamilyINet {
+		l3proto = nftables.TableFamilyUnspecified
+	}
+	var addr []byte
+	if tp.addr != nil {
+		addr = tp.addr.IP.To4()
+		l3proto = nftables.TableFamilyIPv4
+		if tp.addr.IsIPv6() {
+			addr = tp.addr.IP.To16()
+			l3proto = nftables.TableFamilyIPv6
+		}
+		if family != nftables.TableFamilyINet && family != l3proto {
+			return nil, fmt.Errorf("address %s of tproxy does not match %s table", tp.addr.IP.String(), familyName(family))
+		}
+		// [ meta load nfproto => reg 1 ]
+		// [ cmp eq reg 1 0x00000002 ]
+		re = append(re, getExprForL3Guard(family, l3proto)...)
+	}
+	if tp.transparent {
+		// [ socket load transparent => reg 1 ]
+		// [ cmp eq reg 1 0x00000001 ]
+		e, err := getExprForSocket(family, &Socket{Transparent: &tp.transparent})
+		if err != nil {
+			return nil, err
+		}
+		re = append(re, e...)
+	}
+	e := &expr.TProxy{
+		Family:      byte(l3proto),
+		TableFamily: byte(family),
+		RegPort:     1,
+	}
+	if addr != nil {
+		// The address is loaded into reg 1 and the port follows it in reg 2
+		// [ immediate reg 1 0x0100007f ]
+		re = append(re, &expr.Immediate{Register: 1, Data: addr})
+		e.RegAddr = 1
+		e.RegPort = 2
+	}
+	// [ immediate reg 2 0x00003a98 ]
+	re = append(re, &expr.Immediate{Register: e.RegPort, Data: binaryutil.BigEndian.PutUint16(tp.port)})
+	// [ tproxy ip addr reg 1 port reg 2 ]
+	re = append(re, e)
+	if tp.mark != nil {
+		// Packet is marked only when it was assigned to the proxy's socket
+		// [ immediate reg 1 0x00000001 ]
+		// [ meta set mark with reg 1 ]
+		me, err := getExprForMetaMark(tp.mark)
+		if err != nil {
+			return nil, err
+		}
+		re = append(re, me...)
+	}
+
+	return re, nil
+}
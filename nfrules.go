@@ -3,7 +3,9 @@ package nftableslib
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
@@ -105,6 +107,12 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 	if rule.MatchAct != nil {
 		skipL3, skipL4, skipAction = true, true, true
 	}
+	// Reject by icmp or icmpv6 code applies only to packets of the code's protocol, in tables carrying both
+	// families the rule is restricted to them before any other expression, packets of the other family are
+	// neither counted nor accounted by stateful expressions of the rule.
+	if rule.Action != nil && rule.Action.reject != nil && !skipAction {
+		r.Exprs = append(r.Exprs, getExprForRejectGuard(nfr.table.Family, rule.Action.reject)...)
+	}
 	// Counter could be used a standalone key word, in this case it will cound number of
 	// packets and bytes which hit the chain where it is defined.
 	// Counter can also be used before and within any rules.
@@ -113,7 +121,30 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 		r.Exprs = append(r.Exprs, e...)
 	}
 	if rule.Fib != nil {
-		e := getExprForFib(rule.Fib)
+		if e, err = getExprForFib(rule.Fib); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	if rule.Interface != nil {
+		if e, set, err = createIntf(rule.Interface); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set...)
+		r.Exprs = append(r.Exprs, e...)
+	}
+	if rule.L2 != nil && !skipL3 {
+		if e, set, err = createL2(nfr.table.Family, rule); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set...)
+		r.Exprs = append(r.Exprs, e...)
+	}
+	if rule.ARP != nil && !skipL3 {
+		if e, set, err = createARP(nfr.table.Family, rule); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set...)
 		r.Exprs = append(r.Exprs, e...)
 	}
 	if rule.L3 != nil && !skipL3 {
@@ -131,6 +162,18 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 		sets = append(sets, set...)
 		r.Exprs = append(r.Exprs, e...)
 	}
+	if rule.TCPOption != nil && !skipL4 {
+		if e, err = createTCPOption(nfr.table.Family, rule.TCPOption); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	if rule.Socket != nil {
+		if e, err = getExprForSocket(nfr.table.Family, rule.Socket); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
 
 	// If L3Rule or L4Rule did not produce a rule, initialize one to carry
 	// Rule's Action expression
@@ -139,27 +182,76 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 	}
 	// Check if Meta is specified appending to rule's list of expressions
 	if rule.Meta != nil {
+		var me []expr.Any
 		switch {
 		case rule.Meta.Mark != nil:
-			r.Exprs = append(r.Exprs, getExprForMetaMark(rule.Meta.Mark)...)
+			me, err = getExprForMetaMark(rule.Meta.Mark)
 		case len(rule.Meta.Expr) != 0:
-			r.Exprs = append(r.Exprs, getExprForMetaExpr(rule.Meta.Expr)...)
+			me, err = getExprForMetaExpr(rule.Meta.Expr)
+		}
+		if err != nil {
+			return nil, err
 		}
+		r.Exprs = append(r.Exprs, me...)
 	}
-	// Check if Meta is specified appending to rule's list of expressions
+	if len(rule.Conntracks) > 0 {
+		if e, err = getExprForConntracks(nfr.table.Family, rule.Conntracks); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	// Limit and Quota account only packets which met all matching criterias of the rule
+	if rule.Limit != nil {
+		if e, err = getExprForLimit(rule.Limit); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	if rule.Quota != nil {
+		if e, err = getExprForQuota(rule.Quota); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	// Packets dropped by Limit or Quota are not logged
 	if rule.Log != nil {
 		r.Exprs = append(r.Exprs, getExprForLog(rule.Log)...)
 	}
-
-	if len(rule.Conntracks) > 0 {
-		r.Exprs = append(r.Exprs, getExprForConntracks(rule.Conntracks)...)
+	// Dynamic set with stateful expressions is updated before the action, stateful expressions
+	// of its elements define whether the action is executed.
+	if rule.Dynamic != nil && rule.Dynamic.stateful() {
+		e, err = getExprForDynamic(nfr.table.Family, rule.Dynamic)
+		if err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	if len(rule.ObjectRefs) > 0 {
+		if e, err = getExprForObjectRefs(rule.ObjectRefs); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
+	}
+	// TCP options are mangled after all matching criterias of the rule are met
+	if rule.TCPOption != nil && !skipL4 {
+		r.Exprs = append(r.Exprs, getExprForTCPOptionSet(rule.TCPOption)...)
+	}
+	if rule.Meta != nil && len(rule.Meta.Set) != 0 {
+		if e, err = getExprForMetaSet(nfr.table.Family, rule.Meta.Set); err != nil {
+			return nil, err
+		}
+		r.Exprs = append(r.Exprs, e...)
 	}
 
 	if rule.Action != nil && !skipAction {
 		switch {
 		case rule.Action.redirect != nil:
 			if rule.Action.redirect.tproxy {
-				r.Exprs = append(r.Exprs, getExprForTProxyRedirect(rule.Action.redirect.port, nfr.table.Family)...)
+				e, err = getExprForTProxy(nfr.table.Family, &tproxy{port: rule.Action.redirect.port})
+				if err != nil {
+					return nil, err
+				}
+				r.Exprs = append(r.Exprs, e...)
 			} else {
 				r.Exprs = append(r.Exprs, getExprForRedirect(rule.Action.redirect.port, nfr.table.Family)...)
 			}
@@ -168,7 +260,11 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 		case rule.Action.masq != nil:
 			r.Exprs = append(r.Exprs, getExprForMasq(rule.Action.masq)...)
 		case rule.Action.reject != nil:
-			r.Exprs = append(r.Exprs, getExprForReject(rule.Action.reject)...)
+			e, err = getExprForReject(nfr.table.Family, rule)
+			if err != nil {
+				return nil, err
+			}
+			r.Exprs = append(r.Exprs, e...)
 		case rule.Action.loadbalance != nil:
 			e, err := getExprForLoadbalance(nfr, rule.Action.loadbalance)
 			if err != nil {
@@ -176,12 +272,41 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 			}
 			// Adding generated loadbalancing expressions and anonymous set
 			r.Exprs = append(r.Exprs, e...)
+		case rule.Action.nat != nil && (rule.Action.nat.pool != nil || rule.Action.nat.natMap != nil):
+			e, err = getExprForNATMap(nfr, rule.Action.nat)
+			if err != nil {
+				return nil, err
+			}
+			r.Exprs = append(r.Exprs, e...)
 		case rule.Action.nat != nil:
 			e, err = getExprForNAT(nfr.table.Family, rule.Action.nat)
 			if err != nil {
 				return nil, err
 			}
 			r.Exprs = append(r.Exprs, e...)
+		case rule.Action.ct != nil:
+			e, err = getExprForCTSet(nfr, rule.Action.ct)
+			if err != nil {
+				return nil, err
+			}
+			r.Exprs = append(r.Exprs, e...)
+		case rule.Action.notrack:
+			// [ notrack ]
+			r.Exprs = append(r.Exprs, &expr.Notrack{})
+		case rule.Action.queue != nil:
+			r.Exprs = append(r.Exprs, getExprForQueue(rule.Action.queue)...)
+		case rule.Action.dup != nil:
+			e, err = getExprForDup(nfr.table.Family, rule.Action.dup)
+			if err != nil {
+				return nil, err
+			}
+			r.Exprs = append(r.Exprs, e...)
+		case rule.Action.tproxy != nil:
+			e, err = getExprForTProxy(nfr.table.Family, rule.Action.tproxy)
+			if err != nil {
+				return nil, err
+			}
+			r.Exprs = append(r.Exprs, e...)
 		}
 	}
 	if rule.Concat != nil {
@@ -191,7 +316,7 @@ func (nfr *nfRules) buildRule(rule *Rule) (*nfRule, error) {
 		}
 		r.Exprs = append(r.Exprs, e...)
 	}
-	if rule.Dynamic != nil {
+	if rule.Dynamic != nil && !rule.Dynamic.stateful() {
 		e, err = getExprForDynamic(nfr.table.Family, rule.Dynamic)
 		if err != nil {
 			return nil, err
@@ -502,17 +627,10 @@ func (nfr *nfRules) GetRuleHandle(id uint32) (uint64, error) {
 	for _, rule := range rules {
 		if rule.UserData != nil {
 			// Rule ID TLV is stored in last 4 bytes of User data
-			n := make([]byte, 4)
-			// Rule ID TLV 4 bytes:
-			//      [0] - TLV type , must be 0x2
-			//      [1] - Value length, must be 2
-			//      [2:] - 2 bytes carrying Rule ID
-			if rule.UserData[len(rule.UserData)-4] != 0x2 || rule.UserData[len(rule.UserData)-3] != 0x2 {
-				return 0, fmt.Errorf("did not find Rule ID TLV in user data")
+			ruleID, err := ruleIDFromUserData(rule.UserData)
+			if err != nil {
+				return 0, err
 			}
-			// Copy last 2 bytes of user data which carry rule id
-			copy(n[2:], rule.UserData[len(rule.UserData)-2:])
-			ruleID := binaryutil.BigEndian.Uint32(n)
 			if ruleID == id {
 				return rule.Handle, nil
 			}
@@ -585,8 +703,55 @@ type Operator byte
 const (
 	EQ Operator = iota
 	NEQ
+	LT
+	GT
+	LTE
+	GTE
 )
 
+// String returns the name of relational operation as used by nft
+func (op Operator) String() string {
+	switch op {
+	case EQ:
+		return "eq"
+	case NEQ:
+		return "neq"
+	case LT:
+		return "lt"
+	case GT:
+		return "gt"
+	case LTE:
+		return "lte"
+	case GTE:
+		return "gte"
+	}
+
+	return fmt.Sprintf("%d", op)
+}
+
+// validateRelOp checks that relational operation is known, LT, GT, LTE and GTE operations are accepted only
+// when ordered is true, as they make no sense for set lookups, ranges, prefixes, names and flags.
+func validateRelOp(op Operator, ordered bool) error {
+	switch op {
+	case EQ, NEQ:
+		return nil
+	case LT, GT, LTE, GTE:
+		if ordered {
+			return nil
+		}
+		return fmt.Errorf("relational operation %s is not supported, only eq and neq can be used", op)
+	}
+
+	return fmt.Errorf("unknown relational operation %s", op)
+}
+
+// HeaderField defines a value of a packet header field and relational operation used to compare
+// the field with the value.
+type HeaderField struct {
+	Value uint32
+	RelOp Operator
+}
+
 // IPAddrSpec lists possible flavours if specifying ip address, either List or Range can be specified
 type IPAddrSpec struct {
 	List   []*IPAddr
@@ -656,7 +821,161 @@ func (ip *IPAddrSpec) Validate() error {
 		}
 	}
 
-	return nil
+	// Only a single address without a prefix can be compared by LT, GT, LTE and GTE
+	return validateRelOp(ip.RelOp, len(ip.List) == 1 && !ip.List[0].CIDR)
+}
+
+// MACAddrSpec lists possible flavours of specifying MAC address, either List or SetRef can be specified
+type MACAddrSpec struct {
+	List   []net.HardwareAddr
+	SetRef *SetRef
+	RelOp  Operator
+}
+
+// Validate checks MACAddrSpec struct
+func (mac *MACAddrSpec) Validate() error {
+	if len(mac.List) != 0 && mac.SetRef != nil {
+		return fmt.Errorf("either List or SetRef but not both can be specified")
+	}
+	if len(mac.List) == 0 && mac.SetRef == nil {
+		return fmt.Errorf("neither List nor SetRef is specified")
+	}
+	for _, addr := range mac.List {
+		if len(addr) != 6 {
+			return fmt.Errorf("%s is invalid ethernet address", addr.String())
+		}
+	}
+
+	return validateRelOp(mac.RelOp, false)
+}
+
+// VLAN defines parameters of 802.1Q header to match, when VLAN is specified
+// only tagged packets are matched.
+type VLAN struct {
+	// ID defines VLAN id, the value is from 0 to 4095
+	ID *uint16
+	// PCP defines priority code point, the value is from 0 to 7
+	PCP *uint8
+}
+
+// L2Rule contains parameters for L2 based rule, it can be used in tables of bridge and netdev families.
+// EtherType defines a type of ethernet frame's payload, example unix.ETH_P_IP, when VLAN is specified,
+// EtherType is matched against the encapsulated frame type.
+// IBrName and OBrName match the name of the bridge the input or output port belongs to,
+// they can be used only in bridge table.
+type L2Rule struct {
+	Src       *MACAddrSpec
+	Dst       *MACAddrSpec
+	EtherType *uint16
+	VLAN      *VLAN
+	IBrName   string
+	OBrName   string
+	RelOp     Operator
+}
+
+// L2EtherType is a helper function to convert a value of ethernet type
+// to the type required by L2Rule *uint16
+func L2EtherType(etherType int) *uint16 {
+	t := uint16(etherType)
+	return &t
+}
+
+// Validate checks parameters of L2Rule struct
+func (l2 *L2Rule) Validate() error {
+	if l2.Src == nil && l2.Dst == nil && l2.EtherType == nil && l2.VLAN == nil &&
+		l2.IBrName == "" && l2.OBrName == "" {
+		return fmt.Errorf("invalid L2 rule as none of L2 parameters are provided")
+	}
+	if l2.Src != nil {
+		if err := l2.Src.Validate(); err != nil {
+			return err
+		}
+	}
+	if l2.Dst != nil {
+		if err := l2.Dst.Validate(); err != nil {
+			return err
+		}
+	}
+	if l2.VLAN != nil {
+		if l2.VLAN.ID == nil && l2.VLAN.PCP == nil {
+			return fmt.Errorf("either VLAN ID or PCP must be specified")
+		}
+		if l2.VLAN.ID != nil && *l2.VLAN.ID > 4095 {
+			return fmt.Errorf("value of VLAN ID %d is invalid", *l2.VLAN.ID)
+		}
+		if l2.VLAN.PCP != nil && *l2.VLAN.PCP > 7 {
+			return fmt.Errorf("value of VLAN PCP %d is invalid", *l2.VLAN.PCP)
+		}
+	}
+	if len(l2.IBrName) > 15 || len(l2.OBrName) > 15 {
+		return fmt.Errorf("bridge name cannot exceed 15 characters")
+	}
+
+	return validateRelOp(l2.RelOp, false)
+}
+
+// ARP operations
+const (
+	ARPOpRequest uint16 = 1
+	ARPOpReply   uint16 = 2
+)
+
+// ARPRule contains parameters for ARP header based rule, it can be used only in tables of arp family.
+// Only ARP packets carrying ethernet and ipv4 addresses can be matched by sender and target addresses.
+type ARPRule struct {
+	// Operation defines ARP operation, example ARPOpRequest or ARPOpReply
+	Operation *uint16
+	// SHA defines sender hardware address
+	SHA *MACAddrSpec
+	// THA defines target hardware address
+	THA *MACAddrSpec
+	// SPA defines sender protocol address
+	SPA *IPAddrSpec
+	// TPA defines target protocol address
+	TPA   *IPAddrSpec
+	RelOp Operator
+}
+
+// ARPOperation is a helper function to convert a value of ARP operation
+// to the type required by ARPRule *uint16
+func ARPOperation(op uint16) *uint16 {
+	return &op
+}
+
+// Validate checks parameters of ARPRule struct
+func (arp *ARPRule) Validate() error {
+	if arp.Operation == nil && arp.SHA == nil && arp.THA == nil && arp.SPA == nil && arp.TPA == nil {
+		return fmt.Errorf("invalid ARP rule as none of ARP parameters are provided")
+	}
+	for _, mac := range []*MACAddrSpec{arp.SHA, arp.THA} {
+		if mac == nil {
+			continue
+		}
+		if err := mac.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, ip := range []*IPAddrSpec{arp.SPA, arp.TPA} {
+		if ip == nil {
+			continue
+		}
+		if ip.SetRef == nil {
+			if err := ip.Validate(); err != nil {
+				return err
+			}
+		}
+		addrs := append([]*IPAddr{}, ip.List...)
+		if ip.Range[0] != nil && ip.Range[1] != nil {
+			addrs = append(addrs, ip.Range[0], ip.Range[1])
+		}
+		for _, addr := range addrs {
+			if addr.IsIPv6() {
+				return fmt.Errorf("ARP rule supports only ipv4 addresses")
+			}
+		}
+	}
+
+	return validateRelOp(arp.RelOp, false)
 }
 
 // L3Rule contains parameters for L3 based rule, either Source or Destination can be specified
@@ -665,8 +984,25 @@ type L3Rule struct {
 	Dst      *IPAddrSpec
 	Version  *byte
 	Protocol *uint32
-	RelOp    Operator
-	Counter  *Counter
+	ExtHdr   *IPv6ExtHdr
+	// DSCP and ECN match differentiated services code point and explicit congestion notification bits
+	// of ipv4 type of service or ipv6 traffic class.
+	DSCP *HeaderField
+	ECN  *HeaderField
+	// TTL matches ipv4 time to live or ipv6 hop limit.
+	TTL *HeaderField
+	// Length matches ipv4 total length or ipv6 payload length.
+	Length *HeaderField
+	// ID, FragOffset and MoreFragments match identification, fragment offset in 8 octets units
+	// and MF flag of ipv4 header.
+	ID            *HeaderField
+	FragOffset    *HeaderField
+	MoreFragments *bool
+	// FlowLabel and TrafficClass match fields of ipv6 header.
+	FlowLabel    *HeaderField
+	TrafficClass *HeaderField
+	RelOp        Operator
+	Counter      *Counter
 }
 
 // L3Protocol is a helper function to convert a value of L3 protocol
@@ -689,11 +1025,92 @@ func (l3 *L3Rule) Validate() error {
 		}
 	case l3.Version != nil:
 	case l3.Protocol != nil:
+	case l3.ExtHdr != nil:
+	case l3.DSCP != nil || l3.ECN != nil || l3.TTL != nil || l3.Length != nil:
+	case l3.ID != nil || l3.FragOffset != nil || l3.MoreFragments != nil:
+	case l3.FlowLabel != nil || l3.TrafficClass != nil:
 	default:
 		return fmt.Errorf("invalid L3 rule as none of L3 parameters are provided")
 	}
+	if l3.ExtHdr != nil {
+		if l3.Version != nil && *l3.Version != 6 {
+			return fmt.Errorf("extension headers can be matched only in ipv6 packets")
+		}
+		if err := l3.ExtHdr.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, f := range []*HeaderField{l3.DSCP, l3.ECN, l3.TTL, l3.Length, l3.ID, l3.FragOffset, l3.FlowLabel, l3.TrafficClass} {
+		if f == nil {
+			continue
+		}
+		if err := validateRelOp(f.RelOp, true); err != nil {
+			return err
+		}
+	}
 
-	return nil
+	// RelOp applies to Version, Protocol and MoreFragments
+	return validateRelOp(l3.RelOp, false)
+}
+
+// Types of IPv6 extension headers
+const (
+	IPv6ExtHdrHopByHop uint8 = 0
+	IPv6ExtHdrRouting  uint8 = 43
+	IPv6ExtHdrFragment uint8 = 44
+	IPv6ExtHdrDstOpts  uint8 = 60
+)
+
+// Types of IPv6 routing header
+const (
+	// IPv6RoutingType0 is deprecated type 0 routing header (RH0)
+	IPv6RoutingType0 uint8 = 0
+	// IPv6RoutingTypeSRH is segment routing header used by SRv6
+	IPv6RoutingTypeSRH uint8 = 4
+)
+
+// IPv6ExtHdr defines parameters to match IPv6 extension headers. When only Type is specified, packets carrying
+// the extension header are matched, with RelOp NEQ packets without the extension header are matched.
+// Fields of routing and fragment headers can be matched only when Type is IPv6ExtHdrRouting or IPv6ExtHdrFragment.
+type IPv6ExtHdr struct {
+	Type uint8
+	// RoutingType matches the type of routing header, example IPv6RoutingType0 or IPv6RoutingTypeSRH.
+	RoutingType *uint8
+	// SegmentsLeft matches the number of remaining route segments, when more than one value is specified,
+	// values are matched by a set lookup.
+	SegmentsLeft []uint8
+	// FragmentOffset matches the offset of a fragment in 8 octets units.
+	FragmentOffset *uint16
+	// MoreFragments matches M flag of fragment header.
+	MoreFragments *bool
+	// FragmentID matches the identification field of fragment header.
+	FragmentID *uint32
+	RelOp      Operator
+}
+
+// Validate checks parameters of IPv6ExtHdr struct
+func (h *IPv6ExtHdr) Validate() error {
+	switch h.Type {
+	case IPv6ExtHdrHopByHop, IPv6ExtHdrRouting, IPv6ExtHdrFragment, IPv6ExtHdrDstOpts:
+	default:
+		return fmt.Errorf("unsupported extension header type %d", h.Type)
+	}
+	if h.Type != IPv6ExtHdrRouting && (h.RoutingType != nil || len(h.SegmentsLeft) != 0) {
+		return fmt.Errorf("routing type and segments left can be matched only in routing header")
+	}
+	if h.Type != IPv6ExtHdrFragment && (h.FragmentOffset != nil || h.MoreFragments != nil || h.FragmentID != nil) {
+		return fmt.Errorf("fragment offset, flags and identification can be matched only in fragment header")
+	}
+	if h.FragmentOffset != nil && *h.FragmentOffset > 0x1fff {
+		return fmt.Errorf("fragment offset %d exceeds maximum value of 8191", *h.FragmentOffset)
+	}
+	// LT, GT, LTE and GTE can be used only when all matched fields are numeric values
+	ordered := len(h.SegmentsLeft) == 1 || h.FragmentOffset != nil || h.FragmentID != nil
+	if h.RoutingType != nil || len(h.SegmentsLeft) > 1 || h.MoreFragments != nil {
+		ordered = false
+	}
+
+	return validateRelOp(h.RelOp, ordered)
 }
 
 // SetRef defines a reference to a Set/Map/Vmap
@@ -754,21 +1171,115 @@ func (p *Port) Validate() error {
 		return fmt.Errorf("neither List nor Range nor SetRef is specified")
 	}
 
-	return nil
+	// Only a single port can be compared by LT, GT, LTE and GTE
+	return validateRelOp(p.RelOp, len(p.List) == 1)
+}
+
+// ICMP defines parameters to match ICMP or ICMPv6 messages, either Type or SetRef can be specified.
+type ICMP struct {
+	// Type defines a list of message types, when more than one type is specified, types are matched by a set lookup.
+	Type []uint8
+	// Code defines message code, it can be specified only along with a single Type or without Type.
+	// RelOp NEQ cannot be used when both Type and Code are specified, as negating each of them would not
+	// negate their combination.
+	Code *uint8
+	// SetRef defines a reference to a set of message types
+	SetRef *SetRef
+	RelOp  Operator
+}
+
+// Validate checks parameters of ICMP struct
+func (icmp *ICMP) Validate() error {
+	if len(icmp.Type) == 0 && icmp.Code == nil && icmp.SetRef == nil {
+		return fmt.Errorf("neither Type nor Code nor SetRef is specified")
+	}
+	if len(icmp.Type) != 0 && icmp.SetRef != nil {
+		return fmt.Errorf("either Type or SetRef but not both can be specified")
+	}
+	if icmp.Code != nil && (len(icmp.Type) > 1 || icmp.SetRef != nil) {
+		return fmt.Errorf("icmp code can be specified only along with a single Type")
+	}
+	if icmp.Code != nil && len(icmp.Type) != 0 && icmp.RelOp == NEQ {
+		return fmt.Errorf("icmp type and code cannot be matched with NEQ operator")
+	}
+
+	return validateRelOp(icmp.RelOp, false)
+}
+
+// Flags of TCP header
+const (
+	TCPFlagFIN uint8 = 0x01
+	TCPFlagSYN uint8 = 0x02
+	TCPFlagRST uint8 = 0x04
+	TCPFlagPSH uint8 = 0x08
+	TCPFlagACK uint8 = 0x10
+	TCPFlagURG uint8 = 0x20
+	TCPFlagECE uint8 = 0x40
+	TCPFlagCWR uint8 = 0x80
+)
+
+// TCPFlags defines parameters to match flags of TCP header. If Mask is not 0, flags of a packet are masked
+// before the comparison, example Mask: TCPFlagSYN | TCPFlagACK and Value: TCPFlagSYN matches packets initiating
+// a connection. If Mask is 0, flags are compared as is, Value 0 matches packets without any flags set.
+// When List or SetRef is specified, masked flags are matched against a set of flag combinations and Value is not used.
+type TCPFlags struct {
+	Value  uint8
+	Mask   uint8
+	List   []uint8
+	SetRef *SetRef
+	RelOp  Operator
+}
+
+// Validate checks parameters of TCPFlags struct
+func (f *TCPFlags) Validate() error {
+	if len(f.List) != 0 && f.SetRef != nil {
+		return fmt.Errorf("either List or SetRef but not both can be specified")
+	}
+	if (len(f.List) != 0 || f.SetRef != nil) && f.Value != 0 {
+		return fmt.Errorf("value cannot be combined with List or SetRef")
+	}
+	if f.Mask != 0 && f.Value&^f.Mask != 0 {
+		return fmt.Errorf("value %#02x has flags outside of mask %#02x", f.Value, f.Mask)
+	}
+
+	return validateRelOp(f.RelOp, false)
 }
 
-// L4Rule contains parameters for L4 based rule
+// L4Rule contains parameters for L4 based rule. When ICMP is specified, L4Proto can be either
+// unix.IPPROTO_ICMP or unix.IPPROTO_ICMPV6, if L4Proto is 0, it is derived from the family of the table
+// or from the address family of the rule. When TCPFlags is specified, L4Proto can be either unix.IPPROTO_TCP or 0.
 type L4Rule struct {
-	L4Proto uint8
-	Src     *Port
-	Dst     *Port
-	RelOp   Operator
-	Counter *Counter
+	L4Proto  uint8
+	Src      *Port
+	Dst      *Port
+	ICMP     *ICMP
+	TCPFlags *TCPFlags
+	RelOp    Operator
+	Counter  *Counter
 }
 
 // Validate checks parameters of L4Rule struct
 func (l4 *L4Rule) Validate() error {
-	if l4.L4Proto == 0 {
+	if l4.ICMP != nil {
+		if l4.Src != nil || l4.Dst != nil {
+			return fmt.Errorf("ICMP cannot be combined with ports")
+		}
+		if l4.L4Proto != 0 && l4.L4Proto != unix.IPPROTO_ICMP && l4.L4Proto != unix.IPPROTO_ICMPV6 {
+			return fmt.Errorf("ICMP requires L4Proto to be either icmp or icmpv6")
+		}
+		if l4.TCPFlags != nil {
+			return fmt.Errorf("ICMP cannot be combined with TCP flags")
+		}
+		return l4.ICMP.Validate()
+	}
+	if l4.TCPFlags != nil {
+		if l4.L4Proto != 0 && l4.L4Proto != unix.IPPROTO_TCP {
+			return fmt.Errorf("TCP flags require L4Proto to be tcp")
+		}
+		if err := l4.TCPFlags.Validate(); err != nil {
+			return err
+		}
+	} else if l4.L4Proto == 0 {
 		return fmt.Errorf("L4Proto cannot be 0")
 	}
 	if l4.Src != nil {
@@ -785,6 +1296,49 @@ func (l4 *L4Rule) Validate() error {
 	return nil
 }
 
+// Kinds of TCP options
+const (
+	TCPOptionEOL           uint8 = 0
+	TCPOptionNOP           uint8 = 1
+	TCPOptionMSS           uint8 = 2
+	TCPOptionWindow        uint8 = 3
+	TCPOptionSACKPermitted uint8 = 4
+	TCPOptionSACK          uint8 = 5
+	TCPOptionTimestamp     uint8 = 8
+)
+
+// TCPOption defines parameters to match and to mangle options of TCP header.
+type TCPOption struct {
+	// Kind matches packets carrying the option of this kind, with RelOp NEQ packets
+	// without the option are matched.
+	Kind *uint8
+	// MSS compares the value of maximum segment size option.
+	MSS   *uint16
+	RelOp Operator
+	// SetMSS sets maximum segment size option of matching packets to a constant value.
+	SetMSS *uint16
+	// SetMSSToPMTU sets maximum segment size option to the value derived from MTU of the route
+	// to the destination, it is used to clamp MSS on gateways. It is supported only in chains with forward,
+	// output or postrouting hooks.
+	SetMSSToPMTU bool
+}
+
+// Validate checks parameters of TCPOption struct
+func (o *TCPOption) Validate() error {
+	if o.Kind == nil && o.MSS == nil && o.SetMSS == nil && !o.SetMSSToPMTU {
+		return fmt.Errorf("neither Kind nor MSS nor SetMSS nor SetMSSToPMTU is specified")
+	}
+	if o.Kind != nil && o.MSS != nil {
+		return fmt.Errorf("either Kind or MSS but not both can be specified")
+	}
+	if o.SetMSS != nil && o.SetMSSToPMTU {
+		return fmt.Errorf("either SetMSS or SetMSSToPMTU but not both can be specified")
+	}
+
+	// Presence of an option can only be equal or not equal
+	return validateRelOp(o.RelOp, o.MSS != nil)
+}
+
 // redirect defines struct describing Redirection action, if Transparent Proxy is required
 // TProxy should be set
 type redirect struct {
@@ -809,12 +1363,17 @@ type nat struct {
 	persistent  *bool
 	address     *IPAddrSpec
 	port        *Port
+	pool        []*IPAddr
+	natMap      *NATMap
 }
 
-// reject defines reject action
+// reject defines reject action, l3proto is set when icmp code was defined for icmp or icmpv6, typed is set
+// by typed constructors, their reject is validated against the table family and the rule.
 type reject struct {
 	rejectType uint32
 	rejectCode uint8
+	l3proto    uint8
+	typed      bool
 }
 
 // loadbalance defines action to loadbalance between 1 or more chains
@@ -822,6 +1381,35 @@ type loadbalance struct {
 	chains []string
 	action int
 	mode   int
+	// weights defines relative share of traffic sent to each chain, nil when chains share traffic equally
+	weights []int
+	hash    *LoadbalanceHash
+}
+
+// queue defines action passing packets to userspace programs listening on total queues starting from num
+type queue struct {
+	num   uint16
+	total uint16
+	flags expr.QueueFlag
+}
+
+// dup defines action sending a copy of packet to addr via the interface with index dev, or in netdev tables
+// out of the interface with index dev
+type dup struct {
+	addr *IPAddr
+	dev  uint32
+}
+
+// ctset defines action to set a key of connection tracking, the key is set either to value,
+// to packet's mark when fromMetaMark is true, or to the value looked up in zones by the name
+// of packet's input interface
+type ctset struct {
+	key   uint32
+	value []byte
+	// mask defines bits of the key which are set, the rest of bits is preserved
+	mask         []byte
+	fromMetaMark bool
+	zones        map[string]uint16
 }
 
 // MetaMark defines Mark keyword of Meta key
@@ -834,6 +1422,8 @@ type MetaMark struct {
 	Set   bool
 	Value uint32
 	Mask  uint32
+	// RelOp is used only when the mark is matched
+	RelOp Operator
 }
 
 // MetaExpr allows specifing Meta expressions by meta key and its value,
@@ -844,10 +1434,101 @@ type MetaExpr struct {
 	RelOp Operator
 }
 
-// Meta defines parameters used to build nft meta expression
+// Meta defines parameters used to build nft meta expression, Set statements are applied after
+// all matching criterias of the rule are met.
 type Meta struct {
 	Mark *MetaMark
 	Expr []MetaExpr
+	Set  []MetaSet
+}
+
+// IntfKey defines the attribute of interface referred by IntfSpec's SetRef
+type IntfKey uint8
+
+const (
+	// IntfKeyName matches interface name, the set must be of nftables.TypeIFName type
+	IntfKeyName IntfKey = iota
+	// IntfKeyIndex matches interface index, the set must be of nftables.TypeIFIndex type
+	IntfKeyIndex
+	// IntfKeyGroup matches interface group, the set must be of nftables.TypeDevGroup type
+	IntfKeyGroup
+)
+
+// IntfSpec lists possible flavours of specifying interfaces, only one of Name, Index, Group or SetRef
+// can be specified.
+type IntfSpec struct {
+	// Name defines a list of interface names, a name ending with '*' matches all interfaces
+	// which names start with the prefix, example "eth*". Wildcard name cannot be a part of a list.
+	Name []string
+	// Index defines a list of interface indexes
+	Index []uint32
+	// Group defines a list of interface groups
+	Group []uint32
+	// SetRef defines a reference to a named set, Key defines which attribute of interface is looked up.
+	SetRef *SetRef
+	Key    IntfKey
+	RelOp  Operator
+}
+
+// Validate checks IntfSpec struct
+func (intf *IntfSpec) Validate() error {
+	n := 0
+	if len(intf.Name) != 0 {
+		n++
+	}
+	if len(intf.Index) != 0 {
+		n++
+	}
+	if len(intf.Group) != 0 {
+		n++
+	}
+	if intf.SetRef != nil {
+		n++
+	}
+	if n != 1 {
+		return fmt.Errorf("only one of Name, Index, Group or SetRef must be specified")
+	}
+	for _, name := range intf.Name {
+		if name == "" || name == "*" {
+			return fmt.Errorf("interface name cannot be empty")
+		}
+		if len(name) > 15 {
+			return fmt.Errorf("interface name %s exceeds 15 characters", name)
+		}
+		if strings.HasSuffix(name, "*") && len(intf.Name) > 1 {
+			return fmt.Errorf("wildcard interface name %s cannot be a part of a list", name)
+		}
+	}
+	if intf.SetRef != nil && intf.Key > IntfKeyGroup {
+		return fmt.Errorf("invalid interface key %d", intf.Key)
+	}
+
+	return validateRelOp(intf.RelOp, false)
+}
+
+// Interface defines parameters to match input and output interfaces of a packet
+type Interface struct {
+	Input  *IntfSpec
+	Output *IntfSpec
+}
+
+// Validate checks Interface struct
+func (i *Interface) Validate() error {
+	if i.Input == nil && i.Output == nil {
+		return fmt.Errorf("either Input or Output interface must be specified")
+	}
+	if i.Input != nil {
+		if err := i.Input.Validate(); err != nil {
+			return err
+		}
+	}
+	if i.Output != nil {
+		if err := i.Output.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // RuleAction defines what action needs to be executed on the rule match
@@ -858,6 +1539,11 @@ type RuleAction struct {
 	nat         *nat
 	reject      *reject
 	loadbalance *loadbalance
+	ct          *ctset
+	notrack     bool
+	queue       *queue
+	dup         *dup
+	tproxy      *tproxy
 }
 
 // SetLoadbalance builds RuleAction struct for Verdict based actions,
@@ -873,12 +1559,88 @@ func SetLoadbalance(chains []string, action int, mode int) (*RuleAction, error)
 		loadbalance: &loadbalance{
 			chains: chains,
 			action: action,
+			mode:   mode,
 		},
 	}
 
 	return ra, nil
 }
 
+// SetLoadbalanceWeighted builds RuleAction struct for load balancing between chains proportionally to weights,
+// example weights 3 and 1 send three quarters of traffic to the first chain. action and mode parameters are
+// the same as of SetLoadbalance.
+func SetLoadbalanceWeighted(chains []string, weights []int, action int, mode int) (*RuleAction, error) {
+	if err := validateLoadbalanceWeights(chains, weights); err != nil {
+		return nil, err
+	}
+	ra, err := SetLoadbalance(chains, action, mode)
+	if err != nil {
+		return nil, err
+	}
+	ra.loadbalance.weights = weights
+
+	return ra, nil
+}
+
+// LoadbalanceHash defines hash based load balancing, all packets of a flow are sent to the same chain.
+// Chain is selected by jhash of packet's fields defined by Fields, example source address, source address and port
+// or 5-tuple, initialized by Seed. When Symmetric is true, symhash of packet's flow is used instead, both directions of
+// the flow are sent to the same chain, Fields and Seed must not be specified.
+type LoadbalanceHash struct {
+	Fields    []*ConcatElement
+	Seed      uint32
+	Symmetric bool
+}
+
+// Validate checks parameters of LoadbalanceHash struct
+func (h *LoadbalanceHash) Validate() error {
+	if h.Symmetric {
+		if len(h.Fields) != 0 || h.Seed != 0 {
+			return fmt.Errorf("symmetric hash cannot have fields or seed")
+		}
+		return nil
+	}
+	if len(h.Fields) == 0 {
+		return fmt.Errorf("fields of hash cannot be empty")
+	}
+
+	return nil
+}
+
+// SetLoadbalanceHash builds RuleAction struct for hash based load balancing between chains, if weights are not nil,
+// chains receive flows proportionally to weights. action parameter is the same as of SetLoadbalance.
+func SetLoadbalanceHash(chains []string, weights []int, action int, hash *LoadbalanceHash) (*RuleAction, error) {
+	if weights != nil {
+		if err := validateLoadbalanceWeights(chains, weights); err != nil {
+			return nil, err
+		}
+	}
+	if err := hash.Validate(); err != nil {
+		return nil, err
+	}
+	ra, err := SetLoadbalance(chains, action, 0)
+	if err != nil {
+		return nil, err
+	}
+	ra.loadbalance.weights = weights
+	ra.loadbalance.hash = hash
+
+	return ra, nil
+}
+
+func validateLoadbalanceWeights(chains []string, weights []int) error {
+	if len(weights) != len(chains) {
+		return fmt.Errorf("number of weights %d does not match number of chains %d", len(weights), len(chains))
+	}
+	for _, w := range weights {
+		if w < 1 {
+			return fmt.Errorf("invalid weight %d", w)
+		}
+	}
+
+	return nil
+}
+
 // SetVerdict builds RuleAction struct for Verdict based actions
 func SetVerdict(key int, chain ...string) (*RuleAction, error) {
 	ra := &RuleAction{}
@@ -935,12 +1697,16 @@ func SetMasqToPort(port ...int) (*RuleAction, error) {
 // Either L3Addr or Port must be defined.
 // When 2 elements of array are specified, then the range of either ip addresses
 // or ports will be specified in NAT rule.
+// Pool and Map cannot be combined with L3Addr and Port, connections are translated to a random address of Pool
+// or to the address, or the address and the port, selected by Map.
 type NATAttributes struct {
 	L3Addr      [2]*IPAddr
 	Port        [2]uint16
 	FullyRandom bool
 	Random      bool
 	Persistent  bool
+	Pool        []*IPAddr
+	Map         *NATMap
 }
 
 func setNat(nattype expr.NATType, natAttrs *NATAttributes) (*RuleAction, error) {
@@ -954,6 +1720,27 @@ func setNat(nattype expr.NATType, natAttrs *NATAttributes) (*RuleAction, error)
 		random:      &natAttrs.Random,
 		persistent:  &natAttrs.Persistent,
 	}
+	if len(natAttrs.Pool) != 0 || natAttrs.Map != nil {
+		if natAttrs.L3Addr[0] != nil || natAttrs.L3Addr[1] != nil || natAttrs.Port[0] != 0 || natAttrs.Port[1] != 0 {
+			return nil, fmt.Errorf("pool or map cannot be combined with address or port")
+		}
+		if len(natAttrs.Pool) != 0 && natAttrs.Map != nil {
+			return nil, fmt.Errorf("either pool or map but not both can be specified")
+		}
+		for _, addr := range natAttrs.Pool {
+			if err := addr.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		if natAttrs.Map != nil {
+			if err := natAttrs.Map.Validate(); err != nil {
+				return nil, err
+			}
+		}
+		ra.nat.pool = natAttrs.Pool
+		ra.nat.natMap = natAttrs.Map
+		return ra, nil
+	}
 	addr := &IPAddrSpec{}
 	switch {
 	case natAttrs.L3Addr[0] != nil && natAttrs.L3Addr[1] != nil:
@@ -1006,7 +1793,8 @@ func SetDNAT(natAttrs *NATAttributes) (*RuleAction, error) {
 }
 
 // SetReject builds RuleAction struct for Reject action, rt defines Reject type ICMP or TCP
-// rc defines ICMP Reject Code
+// rc defines ICMP Reject Code. Values are not validated, SetRejectTCPReset, SetRejectICMP, SetRejectICMPv6
+// and SetRejectICMPx validate them against the table family and the rule.
 func SetReject(rt int, rc int) (*RuleAction, error) {
 	ra := &RuleAction{
 		reject: &reject{
@@ -1018,6 +1806,218 @@ func SetReject(rt int, rc int) (*RuleAction, error) {
 	return ra, nil
 }
 
+func setTypedReject(rt uint32, rc uint8, l3proto uint8) *RuleAction {
+	return &RuleAction{
+		reject: &reject{
+			rejectType: rt,
+			rejectCode: rc,
+			l3proto:    l3proto,
+			typed:      true,
+		},
+	}
+}
+
+// SetRejectTCPReset builds RuleAction struct for rejecting tcp packets by tcp reset, the rule must match
+// tcp protocol by L4, L3 Protocol or TCPOption.
+func SetRejectTCPReset() (*RuleAction, error) {
+	return setTypedReject(unix.NFT_REJECT_TCP_RST, 0, 0), nil
+}
+
+// SetRejectICMP builds RuleAction struct for rejecting ipv4 packets by icmp destination unreachable message
+// with code, it can be used in ip tables and, matching only ipv4 packets, in inet, bridge and netdev tables.
+func SetRejectICMP(code ICMPRejectCode) (*RuleAction, error) {
+	if code > icmpRejectCodeMax {
+		return nil, fmt.Errorf("invalid icmp reject code %d", code)
+	}
+
+	return setTypedReject(unix.NFT_REJECT_ICMP_UNREACH, uint8(code), unix.NFPROTO_IPV4), nil
+}
+
+// SetRejectICMPv6 builds RuleAction struct for rejecting ipv6 packets by icmpv6 destination unreachable message
+// with code, it can be used in ip6 tables and, matching only ipv6 packets, in inet, bridge and netdev tables.
+func SetRejectICMPv6(code ICMPv6RejectCode) (*RuleAction, error) {
+	if code > icmpv6RejectCodeMax {
+		return nil, fmt.Errorf("invalid icmpv6 reject code %d", code)
+	}
+
+	return setTypedReject(unix.NFT_REJECT_ICMP_UNREACH, uint8(code), unix.NFPROTO_IPV6), nil
+}
+
+// SetRejectICMPx builds RuleAction struct for rejecting packets by icmp or icmpv6 message, depending on
+// the family of packet, with code common for both protocols. In ip and ip6 tables the code is translated
+// to the code of icmp or icmpv6.
+func SetRejectICMPx(code ICMPxRejectCode) (*RuleAction, error) {
+	if code > unix.NFT_REJECT_ICMPX_MAX {
+		return nil, fmt.Errorf("invalid icmpx reject code %d", code)
+	}
+
+	return setTypedReject(unix.NFT_REJECT_ICMPX_UNREACH, uint8(code), 0), nil
+}
+
+// SetCTMark builds RuleAction struct for setting the mark of packet's connection, if mask is not 0,
+// only bits of the mark defined by the mask are set and the rest of bits is preserved.
+func SetCTMark(value, mask uint32) (*RuleAction, error) {
+	ra := &RuleAction{
+		ct: &ctset{
+			key:   unix.NFT_CT_MARK,
+			value: binaryutil.NativeEndian.PutUint32(value),
+		},
+	}
+	if mask != 0 {
+		ra.ct.value = binaryutil.NativeEndian.PutUint32(value & mask)
+		ra.ct.mask = binaryutil.NativeEndian.PutUint32(mask)
+	}
+
+	return ra, nil
+}
+
+// SetCTMarkFromMeta builds RuleAction struct for copying the mark of a packet to the mark of its connection,
+// if mask is not 0, only bits of packet's mark defined by the mask are copied and the rest of bits is cleared.
+func SetCTMarkFromMeta(mask uint32) (*RuleAction, error) {
+	ra := &RuleAction{
+		ct: &ctset{
+			key:          unix.NFT_CT_MARK,
+			fromMetaMark: true,
+		},
+	}
+	if mask != 0 {
+		ra.ct.mask = binaryutil.NativeEndian.PutUint32(mask)
+	}
+
+	return ra, nil
+}
+
+// SetCTZone builds RuleAction struct for setting the zone of packet's connection, the zone must be set
+// before the packet is tracked, in a chain with priority lower than ChainPriorityConntrack, example ChainPriorityRaw.
+func SetCTZone(zone uint16) (*RuleAction, error) {
+	ra := &RuleAction{
+		ct: &ctset{
+			key:   unix.NFT_CT_ZONE,
+			value: binaryutil.NativeEndian.PutUint16(zone),
+		},
+	}
+
+	return ra, nil
+}
+
+// SetCTZoneByInterface builds RuleAction struct for setting the zone of packet's connection to the zone
+// mapped to the name of packet's input interface, packets received from interfaces missing in zones map
+// do not match the rule.
+func SetCTZoneByInterface(zones map[string]uint16) (*RuleAction, error) {
+	if len(zones) == 0 {
+		return nil, fmt.Errorf("map of zones cannot be empty")
+	}
+	for name := range zones {
+		if len(name) == 0 || len(name) >= unix.IFNAMSIZ {
+			return nil, fmt.Errorf("invalid interface name %q", name)
+		}
+	}
+	ra := &RuleAction{
+		ct: &ctset{
+			key:   unix.NFT_CT_ZONE,
+			zones: zones,
+		},
+	}
+
+	return ra, nil
+}
+
+// SetCTLabels builds RuleAction struct for adding labels to packet's connection, a label is the number
+// of the bit in connection's labels bitmask, from 0 to 127.
+func SetCTLabels(labels ...int) (*RuleAction, error) {
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("no label provided")
+	}
+	value := make([]byte, ctLabelsLen)
+	for _, l := range labels {
+		if l < 0 || l >= ctLabelsLen*8 {
+			return nil, fmt.Errorf("value of label %d is invalid", l)
+		}
+		// Labels bitmask is set by words of 4 bytes in the host byte order
+		word := binaryutil.NativeEndian.Uint32(value[l/32*4:]) | 1<<uint(l%32)
+		copy(value[l/32*4:], binaryutil.NativeEndian.PutUint32(word))
+	}
+	ra := &RuleAction{
+		ct: &ctset{
+			key:   unix.NFT_CT_LABELS,
+			value: value,
+		},
+	}
+
+	return ra, nil
+}
+
+// SetNotrack builds RuleAction struct for disabling connection tracking of a packet, it can be used only
+// in chains with prerouting or output hook and priority lower than ChainPriorityConntrack, example ChainPriorityRaw.
+func SetNotrack() (*RuleAction, error) {
+	return &RuleAction{notrack: true}, nil
+}
+
+// QueueAttributes defines parameters of queue action, packets are passed to the queue Num[0], when Num[1] is not 0,
+// packets are distributed among queues from Num[0] to Num[1] by the hash of the flow or, when Fanout is true, by
+// the id of cpu processing the packet. When Bypass is true, packets are accepted if no program listens on the queue.
+// The queue cannot be selected by a map, google/nftables does not support the queue number loaded from a register.
+type QueueAttributes struct {
+	Num    [2]uint16
+	Bypass bool
+	Fanout bool
+}
+
+// SetQueue builds RuleAction struct for Queue action
+func SetQueue(queueAttrs *QueueAttributes) (*RuleAction, error) {
+	if queueAttrs == nil {
+		return nil, fmt.Errorf("queue attributes cannot be nil")
+	}
+	total := uint32(1)
+	if queueAttrs.Num[1] != 0 {
+		if queueAttrs.Num[1] < queueAttrs.Num[0] {
+			return nil, fmt.Errorf("invalid range of queues %d-%d", queueAttrs.Num[0], queueAttrs.Num[1])
+		}
+		total = uint32(queueAttrs.Num[1]) - uint32(queueAttrs.Num[0]) + 1
+	}
+	// The number of queues is carried by 16 bits, all 65536 queues cannot be used
+	if total > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid range of queues %d-%d, number of queues %d exceeds %d", queueAttrs.Num[0],
+			queueAttrs.Num[1], total, math.MaxUint16)
+	}
+	ra := &RuleAction{
+		queue: &queue{
+			num:   queueAttrs.Num[0],
+			total: uint16(total),
+		},
+	}
+	if queueAttrs.Bypass {
+		ra.queue.flags |= expr.QueueFlagBypass
+	}
+	if queueAttrs.Fanout {
+		ra.queue.flags |= expr.QueueFlagFanout
+	}
+
+	return ra, nil
+}
+
+// SetDup builds RuleAction struct for Dup action, in ip and ip6 tables, a copy of packet is sent to addr, if dev is not 0,
+// the copy is sent via the interface with index dev. In netdev tables, addr must be nil and the copy is sent out of
+// the interface with index dev. Packets cannot be forwarded by fwd to dev, google/nftables does not support fwd expression.
+func SetDup(addr *IPAddr, dev uint32) (*RuleAction, error) {
+	if addr == nil && dev == 0 {
+		return nil, fmt.Errorf("either address or device must be specified")
+	}
+	if addr != nil {
+		if err := validateHostAddr(addr); err != nil {
+			return nil, err
+		}
+	}
+	ra := &RuleAction{
+		dup: &dup{
+			addr: addr,
+			dev:  dev,
+		},
+	}
+
+	return ra, nil
+}
+
 // Validate method validates RuleAction parameters and returns error if inconsistency if found
 func (ra *RuleAction) Validate() error {
 	if ra.verdict == nil && ra.redirect == nil {
@@ -1072,6 +2072,61 @@ type Log struct {
 type Counter struct {
 }
 
+// LimitUnit defines the time unit of the rate of Limit
+type LimitUnit uint64
+
+// Time units of the rate of Limit
+const (
+	LimitPerSecond LimitUnit = LimitUnit(expr.LimitTimeSecond)
+	LimitPerMinute LimitUnit = LimitUnit(expr.LimitTimeMinute)
+	LimitPerHour   LimitUnit = LimitUnit(expr.LimitTimeHour)
+	LimitPerDay    LimitUnit = LimitUnit(expr.LimitTimeDay)
+)
+
+// Limit defines the rate limit of the rule, the rule matches packets until the rate of Rate packets
+// per Unit is reached, Burst defines the number of packets allowed to exceed the rate.
+// When Bytes is true, Rate and Burst are defined in bytes, when Over is true, the rule matches
+// only packets exceeding the rate.
+type Limit struct {
+	Rate  uint64
+	Unit  LimitUnit
+	Burst uint32
+	Bytes bool
+	Over  bool
+}
+
+// Validate checks parameters of Limit
+func (l *Limit) Validate() error {
+	if l.Rate == 0 {
+		return fmt.Errorf("rate of limit cannot be 0")
+	}
+	switch l.Unit {
+	case LimitPerSecond, LimitPerMinute, LimitPerHour, LimitPerDay:
+	default:
+		return fmt.Errorf("invalid unit %d of limit", l.Unit)
+	}
+
+	return nil
+}
+
+// Quota defines the quota of bytes of the rule, the rule matches packets until Bytes are consumed,
+// Consumed defines the number of bytes already consumed. When Over is true, the rule matches only
+// packets exceeding the quota.
+type Quota struct {
+	Bytes    uint64
+	Consumed uint64
+	Over     bool
+}
+
+// Validate checks parameters of Quota
+func (q *Quota) Validate() error {
+	if q.Bytes == 0 {
+		return fmt.Errorf("bytes of quota cannot be 0")
+	}
+
+	return nil
+}
+
 // Fib defines nftables Fib expression. Results and Flags can have multiple selections.
 // Data is a slice of bytes, its content depends up on Result and Flags combination.
 // Example: if fib expression specifies a particular address type, then Data would carry one of
@@ -1123,10 +2178,41 @@ var (
 	CTStateInvalid     uint32 = 0x01000000
 )
 
-// Conntrack defines a key and  value for Ccnnection tracking
+// Define Status bits of Connection tracking Status key, values follow the format of CTState values
+var (
+	CTStatusExpected  uint32 = 0x01000000
+	CTStatusSeenReply uint32 = 0x02000000
+	CTStatusAssured   uint32 = 0x04000000
+	CTStatusConfirmed uint32 = 0x08000000
+	CTStatusSNAT      uint32 = 0x10000000
+	CTStatusDNAT      uint32 = 0x20000000
+	CTStatusDying     uint32 = 0x00020000
+)
+
+// Directions of a connection, they are used as a value of unix.NFT_CT_DIRECTION key
+// and as Direction of Conntrack
+const (
+	CTDirectionOriginal uint8 = 0
+	CTDirectionReply    uint8 = 1
+)
+
+// Conntrack defines a key and  value for Ccnnection tracking. Value carries data in the format of the key:
+//   - unix.NFT_CT_STATE, unix.NFT_CT_STATUS and unix.NFT_CT_LABELS are bitmasks, the key matches when any bit
+//     of Value is set, with RelOp NEQ when none of bits is set.
+//   - unix.NFT_CT_MARK, unix.NFT_CT_EXPIRATION (in milliseconds) and unix.NFT_CT_ZONE carry 4, 4 and 2 bytes
+//     in the host byte order.
+//   - unix.NFT_CT_DIRECTION, unix.NFT_CT_L3PROTOCOL and unix.NFT_CT_PROTOCOL carry a single byte.
+//   - unix.NFT_CT_SRC and unix.NFT_CT_DST carry ipv4 or ipv6 address, unix.NFT_CT_PROTO_SRC and unix.NFT_CT_PROTO_DST
+//     carry a port in the network byte order, Direction selects the tuple of original or reply direction.
+//     Direction cannot be used with other keys.
+//
+// Mask is applied to the value of the key before the comparison, it cannot be used with bitmask keys.
 type Conntrack struct {
-	Key   uint32
-	Value []byte
+	Key       uint32
+	Value     []byte
+	Mask      []byte
+	Direction uint8
+	RelOp     Operator
 }
 
 // MatchType defines a matching criteria for an incoming packet. Only one of the criterias
@@ -1157,6 +2243,30 @@ type Dynamic struct {
 	// Timeout defines an aging timeout for a new entry.
 	Timeout time.Duration
 	Invert  bool
+	// L3Proto defines the address family, nftables.TableFamilyIPv4 or nftables.TableFamilyIPv6,
+	// of matched addresses. It is required only when the rule is programmed into inet table.
+	L3Proto nftables.TableFamily
+	// Counter, Limit, Quota and ConnLimit define stateful expressions attached to each element of
+	// the set, they are evaluated per element and the rule matches only when all of them match.
+	// Example, Limit defines the rate limit per source address. The set must be created with
+	// Dynamic attribute.
+	Counter   *Counter
+	Limit     *Limit
+	Quota     *Quota
+	ConnLimit *ConnLimit
+}
+
+// stateful returns true when stateful expressions are attached to elements of the set
+func (d *Dynamic) stateful() bool {
+	return d.Counter != nil || d.Limit != nil || d.Quota != nil || d.ConnLimit != nil
+}
+
+// ConnLimit defines the limit of the number of connections, it matches while the number of
+// connections does not exceed Count, when Over is true, it matches only when the number of
+// connections is over Count.
+type ConnLimit struct {
+	Count uint32
+	Over  bool
 }
 
 // MatchAct rule defines a special type of rules (no support yet by nft cli tool), where matching
@@ -1169,6 +2279,9 @@ type MatchAct struct {
 	// ActElements defines a slice elements of type { integer : action }, these will be placed into
 	// the anonymous action map.
 	ActElement map[int]*RuleAction
+	// L3Proto defines the address family, nftables.TableFamilyIPv4 or nftables.TableFamilyIPv6,
+	// of matched addresses. It is required only when the rule is programmed into inet table.
+	L3Proto nftables.TableFamily
 }
 
 // Rule contains parameters for a rule to configure, only L3 OR L4 parameters can be specified
@@ -1177,13 +2290,22 @@ type Rule struct {
 	Dynamic    *Dynamic
 	MatchAct   *MatchAct
 	Fib        *Fib
+	Interface  *Interface
+	L2         *L2Rule
+	ARP        *ARPRule
 	L3         *L3Rule
 	L4         *L4Rule
+	TCPOption  *TCPOption
 	Conntracks []*Conntrack
+	Socket     *Socket
 	Meta       *Meta
 	Log        *Log
 	RelOp      Operator
 	Counter    *Counter
+	Limit      *Limit
+	Quota      *Quota
+	// ObjectRefs defines references to named counters, quotas and limits of the table
+	ObjectRefs []*ObjectRef
 	Action     *RuleAction
 	UserData   []byte
 	// Position identifies the desired position of the rule, depending on the operation
@@ -1198,6 +2320,21 @@ type Rule struct {
 
 // Validate checks parameters passed in struct and returns error if inconsistency is found
 func (r Rule) Validate() error {
+	if r.Interface != nil {
+		if err := r.Interface.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.L2 != nil {
+		if err := r.L2.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.ARP != nil {
+		if err := r.ARP.Validate(); err != nil {
+			return err
+		}
+	}
 	switch {
 	case r.L3 != nil:
 		if err := r.L3.Validate(); err != nil {
@@ -1208,6 +2345,41 @@ func (r Rule) Validate() error {
 			return err
 		}
 	}
+	if r.TCPOption != nil {
+		if err := r.TCPOption.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Socket != nil {
+		if err := r.Socket.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Limit != nil {
+		if err := r.Limit.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Quota != nil {
+		if err := r.Quota.Validate(); err != nil {
+			return err
+		}
+	}
+	if r.Meta != nil {
+		for _, m := range r.Meta.Set {
+			if err := m.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ref := range r.ObjectRefs {
+		if ref == nil {
+			continue
+		}
+		if err := ref.Validate(); err != nil {
+			return err
+		}
+	}
 	if r.Action == nil {
 		return nil
 	}
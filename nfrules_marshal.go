@@ -3,6 +3,7 @@ package nftableslib
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"strings"
 
 	"github.com/google/nftables"
@@ -54,21 +55,30 @@ func buildIPv6String(b []byte) string {
 	return strings.Join(s, "")
 }
 
-func marshalSetElements(elements []nftables.SetElement) ([]byte, error) {
+func marshalSetElements(keyType nftables.SetDatatype, elements []nftables.SetElement) ([]byte, error) {
 	var jsonData []byte
 	jsonData = append(jsonData, '[')
 
 	for i, element := range elements {
 		jsonData = append(jsonData, '{')
 		jsonData = append(jsonData, []byte("\"Key\":")...)
-		switch len(element.Key) {
-		case 4:
+		switch {
+		case keyType.Name == nftables.TypeIFName.Name:
+			// It is interface name
+			jsonData = append(jsonData, []byte(fmt.Sprintf("\"%s\"", strings.TrimRight(string(element.Key), "\x00")))...)
+		case keyType.Name == nftables.TypeIFIndex.Name || keyType.Name == nftables.TypeDevGroup.Name:
+			// It is interface index or group
+			jsonData = append(jsonData, []byte(fmt.Sprintf("\"%d\"", binaryutil.NativeEndian.Uint32(element.Key)))...)
+		case len(element.Key) == 4:
 			// It is IPv4 address
 			jsonData = append(jsonData, []byte(fmt.Sprintf("\"%d.%d.%d.%d\"", element.Key[0], element.Key[1], element.Key[2], element.Key[3]))...)
-		case 16:
+		case len(element.Key) == 16:
 			// It is IPv6 address
 			jsonData = append(jsonData, []byte(fmt.Sprintf("\"%s\"", buildIPv6String(element.Key)))...)
-		case 2:
+		case len(element.Key) == 6:
+			// It is MAC address
+			jsonData = append(jsonData, []byte(fmt.Sprintf("\"%s\"", net.HardwareAddr(element.Key).String()))...)
+		case len(element.Key) == 2:
 			// It is a port
 			b := []byte{0x0, 0x0}
 			b = append(b, element.Key...)
@@ -121,7 +131,7 @@ func (nfr *nfRule) MarshalJSON() ([]byte, error) {
 		}
 		jsonData = append(jsonData, ',')
 		jsonData = append(jsonData, s...)
-		e, err := marshalSetElements(set.elements)
+		e, err := marshalSetElements(set.set.KeyType, set.elements)
 		if err != nil {
 			return nil, err
 		}
@@ -296,6 +306,8 @@ func marshalExpression(exp expr.Any) ([]byte, error) {
 		b = append(b, []byte(fmt.Sprintf("%d", e.Family))...)
 		b = append(b, []byte(",\"TableFamily\":")...)
 		b = append(b, []byte(fmt.Sprintf("%d", e.TableFamily))...)
+		b = append(b, []byte(",\"RegAddr\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%#x\"", e.RegAddr))...)
 		b = append(b, []byte(",\"RegPort\":")...)
 		b = append(b, []byte(fmt.Sprintf("\"%#x\"}", e.RegPort))...)
 		return b, nil
@@ -444,15 +456,286 @@ func marshalExpression(exp expr.Any) ([]byte, error) {
 		b = append(b, '}')
 		return b, nil
 	}
+	if e, ok := exp.(*expr.Dynset); ok {
+		return marshalDynset(e)
+	}
+	if e, ok := exp.(*expr.Counter); ok {
+		b = append(b, []byte("{\"Bytes\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Bytes))...)
+		b = append(b, []byte(",\"Packets\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Packets))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Connlimit); ok {
+		b = append(b, []byte("{\"Count\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Count))...)
+		b = append(b, []byte(",\"Flags\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Flags))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Exthdr); ok {
+		b = append(b, []byte("{\"Op\":")...)
+		switch e.Op {
+		case expr.ExthdrOpIpv6:
+			b = append(b, []byte("\"expr.ExthdrOpIpv6\"")...)
+		case expr.ExthdrOpTcpopt:
+			b = append(b, []byte("\"expr.ExthdrOpTcpopt\"")...)
+		default:
+			b = append(b, []byte("\"Unknown Op\"")...)
+		}
+		b = append(b, []byte(",\"DestRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.DestRegister))...)
+		b = append(b, []byte(",\"SourceRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.SourceRegister))...)
+		b = append(b, []byte(",\"Type\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Type))...)
+		b = append(b, []byte(",\"Offset\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Offset))...)
+		b = append(b, []byte(",\"Len\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Len))...)
+		b = append(b, []byte(",\"Flags\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%#x\"}", e.Flags))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Rt); ok {
+		b = append(b, []byte("{\"Key\":")...)
+		switch e.Key {
+		case expr.RtClassid:
+			b = append(b, []byte("\"expr.RtClassid\"")...)
+		case expr.RtNexthop4:
+			b = append(b, []byte("\"expr.RtNexthop4\"")...)
+		case expr.RtNexthop6:
+			b = append(b, []byte("\"expr.RtNexthop6\"")...)
+		case expr.RtTCPMSS:
+			b = append(b, []byte("\"expr.RtTCPMSS\"")...)
+		default:
+			b = append(b, []byte("\"Unknown key\"")...)
+		}
+		b = append(b, []byte(",\"Register\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Register))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Ct); ok {
+		b = append(b, []byte("{\"Key\":")...)
+		switch e.Key {
+		case expr.CtKeySTATE:
+			b = append(b, []byte("\"expr.CtKeySTATE\"")...)
+		case expr.CtKeyDIRECTION:
+			b = append(b, []byte("\"expr.CtKeyDIRECTION\"")...)
+		case expr.CtKeySTATUS:
+			b = append(b, []byte("\"expr.CtKeySTATUS\"")...)
+		case expr.CtKeyMARK:
+			b = append(b, []byte("\"expr.CtKeyMARK\"")...)
+		case expr.CtKeySECMARK:
+			b = append(b, []byte("\"expr.CtKeySECMARK\"")...)
+		case expr.CtKeyEXPIRATION:
+			b = append(b, []byte("\"expr.CtKeyEXPIRATION\"")...)
+		case expr.CtKeyHELPER:
+			b = append(b, []byte("\"expr.CtKeyHELPER\"")...)
+		case expr.CtKeyL3PROTOCOL:
+			b = append(b, []byte("\"expr.CtKeyL3PROTOCOL\"")...)
+		case expr.CtKeySRC:
+			b = append(b, []byte("\"expr.CtKeySRC\"")...)
+		case expr.CtKeyDST:
+			b = append(b, []byte("\"expr.CtKeyDST\"")...)
+		case expr.CtKeyPROTOCOL:
+			b = append(b, []byte("\"expr.CtKeyPROTOCOL\"")...)
+		case expr.CtKeyPROTOSRC:
+			b = append(b, []byte("\"expr.CtKeyPROTOSRC\"")...)
+		case expr.CtKeyPROTODST:
+			b = append(b, []byte("\"expr.CtKeyPROTODST\"")...)
+		case expr.CtKeyLABELS:
+			b = append(b, []byte("\"expr.CtKeyLABELS\"")...)
+		case expr.CtKeyPKTS:
+			b = append(b, []byte("\"expr.CtKeyPKTS\"")...)
+		case expr.CtKeyBYTES:
+			b = append(b, []byte("\"expr.CtKeyBYTES\"")...)
+		case expr.CtKeyAVGPKT:
+			b = append(b, []byte("\"expr.CtKeyAVGPKT\"")...)
+		case expr.CtKeyZONE:
+			b = append(b, []byte("\"expr.CtKeyZONE\"")...)
+		case expr.CtKeyEVENTMASK:
+			b = append(b, []byte("\"expr.CtKeyEVENTMASK\"")...)
+		default:
+			b = append(b, []byte("\"Unknown key\"")...)
+		}
+		b = append(b, []byte(",\"Register\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Register))...)
+		b = append(b, []byte(",\"SourceRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%t\"", e.SourceRegister))...)
+		b = append(b, []byte(",\"Direction\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Direction))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Byteorder); ok {
+		b = append(b, []byte("{\"SourceRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.SourceRegister))...)
+		b = append(b, []byte(",\"DestRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.DestRegister))...)
+		b = append(b, []byte(",\"Op\":")...)
+		switch e.Op {
+		case expr.ByteorderNtoh:
+			b = append(b, []byte("\"expr.ByteorderNtoh\"")...)
+		case expr.ByteorderHton:
+			b = append(b, []byte("\"expr.ByteorderHton\"")...)
+		default:
+			b = append(b, []byte("\"Unknown Op\"")...)
+		}
+		b = append(b, []byte(",\"Len\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Len))...)
+		b = append(b, []byte(",\"Size\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Size))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Limit); ok {
+		b = append(b, []byte("{\"Type\":")...)
+		switch e.Type {
+		case expr.LimitTypePkts:
+			b = append(b, []byte("\"expr.LimitTypePkts\"")...)
+		case expr.LimitTypePktBytes:
+			b = append(b, []byte("\"expr.LimitTypePktBytes\"")...)
+		default:
+			b = append(b, []byte("\"Unknown type\"")...)
+		}
+		b = append(b, []byte(",\"Rate\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Rate))...)
+		b = append(b, []byte(",\"Unit\":")...)
+		switch e.Unit {
+		case expr.LimitTimeSecond:
+			b = append(b, []byte("\"expr.LimitTimeSecond\"")...)
+		case expr.LimitTimeMinute:
+			b = append(b, []byte("\"expr.LimitTimeMinute\"")...)
+		case expr.LimitTimeHour:
+			b = append(b, []byte("\"expr.LimitTimeHour\"")...)
+		case expr.LimitTimeDay:
+			b = append(b, []byte("\"expr.LimitTimeDay\"")...)
+		case expr.LimitTimeWeek:
+			b = append(b, []byte("\"expr.LimitTimeWeek\"")...)
+		default:
+			b = append(b, []byte("\"Unknown unit\"")...)
+		}
+		b = append(b, []byte(",\"Burst\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Burst))...)
+		b = append(b, []byte(",\"Over\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%t\"}", e.Over))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Quota); ok {
+		b = append(b, []byte("{\"Bytes\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Bytes))...)
+		b = append(b, []byte(",\"Consumed\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Consumed))...)
+		b = append(b, []byte(",\"Over\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%t\"}", e.Over))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Objref); ok {
+		b = append(b, []byte("{\"Type\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Type))...)
+		b = append(b, []byte(",\"Name\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%s\"}", e.Name))...)
+		return b, nil
+	}
+	if _, ok := exp.(*expr.Notrack); ok {
+		b = append(b, []byte("{\"Notrack\":\"true\"}")...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Numgen); ok {
+		b = append(b, []byte("{\"Register\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Register))...)
+		b = append(b, []byte(",\"Modulus\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Modulus))...)
+		b = append(b, []byte(",\"Type\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Type))...)
+		b = append(b, []byte(",\"Offset\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Offset))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Hash); ok {
+		b = append(b, []byte("{\"SourceRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.SourceRegister))...)
+		b = append(b, []byte(",\"DestRegister\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.DestRegister))...)
+		b = append(b, []byte(",\"Length\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Length))...)
+		b = append(b, []byte(",\"Modulus\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Modulus))...)
+		b = append(b, []byte(",\"Seed\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Seed))...)
+		b = append(b, []byte(",\"Offset\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Offset))...)
+		b = append(b, []byte(",\"Type\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Type))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Dup); ok {
+		b = append(b, []byte("{\"RegAddr\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.RegAddr))...)
+		b = append(b, []byte(",\"RegDev\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.RegDev))...)
+		b = append(b, []byte(",\"IsRegDevSet\":")...)
+		b = append(b, []byte(fmt.Sprintf("%t}", e.IsRegDevSet))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Socket); ok {
+		b = append(b, []byte("{\"Key\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Key))...)
+		b = append(b, []byte(",\"Register\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Register))...)
+		b = append(b, []byte(",\"Level\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d}", e.Level))...)
+		return b, nil
+	}
+	if e, ok := exp.(*expr.Queue); ok {
+		b = append(b, []byte("{\"Num\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Num))...)
+		b = append(b, []byte(",\"Total\":")...)
+		b = append(b, []byte(fmt.Sprintf("%d", e.Total))...)
+		b = append(b, []byte(",\"Flag\":")...)
+		b = append(b, []byte(fmt.Sprintf("\"%#x\"}", e.Flag))...)
+		return b, nil
+	}
 	/*
 		TODO: (sbezverk)
 			expr.Masq:
 			expr.Meta:
 			expr.NAT:
-			expr.Objref:
-			expr.Queue:
-			expr.Rt:
 	*/
 
 	return nil, fmt.Errorf("unknown expression type %T", exp)
 }
+
+// marshalDynset marshals dynamic set update along with stateful expressions attached to the set's elements
+func marshalDynset(e *expr.Dynset) ([]byte, error) {
+	var b []byte
+	b = append(b, []byte("{\"SrcRegKey\":")...)
+	b = append(b, []byte(fmt.Sprintf("%d", e.SrcRegKey))...)
+	b = append(b, []byte(",\"SrcRegData\":")...)
+	b = append(b, []byte(fmt.Sprintf("%d", e.SrcRegData))...)
+	b = append(b, []byte(",\"SetID\":")...)
+	b = append(b, []byte(fmt.Sprintf("%d", e.SetID))...)
+	b = append(b, []byte(",\"SetName\":")...)
+	b = append(b, []byte(fmt.Sprintf("\"%s\"", e.SetName))...)
+	b = append(b, []byte(",\"Operation\":")...)
+	b = append(b, []byte(fmt.Sprintf("%d", e.Operation))...)
+	b = append(b, []byte(",\"Timeout\":")...)
+	b = append(b, []byte(fmt.Sprintf("\"%s\"", e.Timeout))...)
+	b = append(b, []byte(",\"Invert\":")...)
+	b = append(b, []byte(fmt.Sprintf("\"%t\"", e.Invert))...)
+	if len(e.Exprs) != 0 {
+		b = append(b, []byte(",\"Exprs\":[")...)
+		for i, se := range e.Exprs {
+			s, err := marshalExpression(se)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, s...)
+			if i < len(e.Exprs)-1 {
+				b = append(b, ',')
+			}
+		}
+		b = append(b, ']')
+	}
+	b = append(b, '}')
+	return b, nil
+}
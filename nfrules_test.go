@@ -1,8 +1,15 @@
 package nftableslib
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
 	"golang.org/x/sys/unix"
 )
 
@@ -101,6 +108,109 @@ func TestRule(t *testing.T) {
 			},
 			success: true,
 		},
+		{
+			name: "Good L3 Protocol not equal",
+			rule: &Rule{
+				L3: &L3Rule{
+					Protocol: L3Protocol(unix.IPPROTO_TCP),
+					RelOp:    NEQ,
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "Bad L3 Protocol less than",
+			rule: &Rule{
+				L3: &L3Rule{
+					Protocol: L3Protocol(unix.IPPROTO_TCP),
+					RelOp:    LT,
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: false,
+		},
+		{
+			name: "Bad L3 prefix greater than",
+			rule: &Rule{
+				L3: &L3Rule{
+					Src: &IPAddrSpec{
+						List:  []*IPAddr{setIPAddr(t, "192.0.2.0/24")},
+						RelOp: GT,
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: false,
+		},
+		{
+			name: "Good L4 single port less than",
+			rule: &Rule{
+				L4: &L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &Port{
+						List:  SetPortList([]int{1024}),
+						RelOp: LT,
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: true,
+		},
+		{
+			name: "Bad L4 port range greater or equal",
+			rule: &Rule{
+				L4: &L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &Port{
+						Range: SetPortRange([2]int{1024, 2048}),
+						RelOp: GTE,
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: false,
+		},
+		{
+			name: "Bad unknown operator",
+			rule: &Rule{
+				L4: &L4Rule{
+					L4Proto: unix.IPPROTO_TCP,
+					Dst: &Port{
+						List:  SetPortList([]int{1024}),
+						RelOp: Operator(100),
+					},
+				},
+				Action: setActionVerdict(t, unix.NFT_RETURN),
+			},
+			success: false,
+		},
+		{
+			name: "Good Meta set secmark",
+			rule: &Rule{
+				Meta: &Meta{
+					Set: []MetaSet{{Key: unix.NFT_META_SECMARK, Object: "sshtag"}},
+				},
+			},
+			success: true,
+		},
+		{
+			name: "Bad Meta set secmark from map",
+			rule: &Rule{
+				Meta: &Meta{
+					Set: []MetaSet{
+						{
+							Key: unix.NFT_META_SECMARK,
+							Map: &MetaSetMap{
+								Key:    []*ConcatElement{{EType: nftables.TypeInetService}},
+								SetRef: &SetRef{Name: "secmarks", ID: 1},
+							},
+						},
+					},
+				},
+			},
+			success: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,3 +224,1041 @@ func TestRule(t *testing.T) {
 		}
 	}
 }
+
+func TestProtocolRelOp(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      Operator
+		cmpOp   expr.CmpOp
+		success bool
+	}{
+		{
+			name:    "Protocol equal",
+			op:      EQ,
+			cmpOp:   expr.CmpOpEq,
+			success: true,
+		},
+		{
+			name:    "Protocol not equal",
+			op:      NEQ,
+			cmpOp:   expr.CmpOpNeq,
+			success: true,
+		},
+		{
+			name:    "Protocol greater than",
+			op:      GT,
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		re, err := getExprForProtocol(nftables.TableFamilyIPv4, unix.IPPROTO_TCP, tt.op)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		cmp, ok := re[len(re)-1].(*expr.Cmp)
+		if !ok {
+			t.Errorf("Test \"%s\" failed, the last expression is %T and not *expr.Cmp", tt.name, re[len(re)-1])
+			continue
+		}
+		if cmp.Op != tt.cmpOp {
+			t.Errorf("Test \"%s\" failed, cmp operation is %d but supposed to be %d", tt.name, cmp.Op, tt.cmpOp)
+		}
+	}
+}
+
+func ctLabelsWords(words ...uint32) []byte {
+	b := []byte{}
+	for _, w := range words {
+		b = append(b, binaryutil.NativeEndian.PutUint32(w)...)
+	}
+	return b
+}
+
+func TestSetCTLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  []int
+		value   []byte
+		success bool
+	}{
+		{
+			name:    "Labels of first and third words",
+			labels:  []int{0, 2, 70},
+			value:   ctLabelsWords(0x5, 0x0, 0x40, 0x0),
+			success: true,
+		},
+		{
+			name:    "Label of the last bit",
+			labels:  []int{127},
+			value:   ctLabelsWords(0x0, 0x0, 0x0, 0x80000000),
+			success: true,
+		},
+		{
+			name:    "Label out of range",
+			labels:  []int{128},
+			success: false,
+		},
+		{
+			name:    "No labels",
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		ra, err := SetCTLabels(tt.labels...)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if !bytes.Equal(ra.ct.value, tt.value) {
+			t.Errorf("Test \"%s\" failed, labels bitmask is %x but supposed to be %x", tt.name, ra.ct.value, tt.value)
+		}
+	}
+}
+
+func TestDynamicFamily(t *testing.T) {
+	tests := []struct {
+		name    string
+		family  nftables.TableFamily
+		dynamic *Dynamic
+		guard   bool
+		offset  uint32
+		length  uint32
+		success bool
+	}{
+		{
+			name:    "ipv4 source",
+			family:  nftables.TableFamilyIPv4,
+			dynamic: &Dynamic{Match: MatchTypeL3Src, SetRef: &SetRef{Name: "set-1"}},
+			offset:  12,
+			length:  4,
+			success: true,
+		},
+		{
+			name:    "inet ipv4 source",
+			family:  nftables.TableFamilyINet,
+			dynamic: &Dynamic{Match: MatchTypeL3Src, SetRef: &SetRef{Name: "set-1"}, L3Proto: nftables.TableFamilyIPv4},
+			guard:   true,
+			offset:  12,
+			length:  4,
+			success: true,
+		},
+		{
+			name:    "inet ipv6 destination",
+			family:  nftables.TableFamilyINet,
+			dynamic: &Dynamic{Match: MatchTypeL3Dst, SetRef: &SetRef{Name: "set-1"}, L3Proto: nftables.TableFamilyIPv6},
+			guard:   true,
+			offset:  24,
+			length:  16,
+			success: true,
+		},
+		{
+			name:    "inet without address family",
+			family:  nftables.TableFamilyINet,
+			dynamic: &Dynamic{Match: MatchTypeL3Src, SetRef: &SetRef{Name: "set-1"}},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		re, err := getExprForDynamic(tt.family, tt.dynamic)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if tt.guard {
+			// [ meta load nfproto => reg 1 ]
+			// [ cmp eq reg 1 0x00000002 ]
+			m, ok := re[0].(*expr.Meta)
+			if !ok || m.Key != expr.MetaKeyNFPROTO {
+				t.Errorf("Test \"%s\" failed, expression %+v is not nfproto guard", tt.name, re[0])
+				continue
+			}
+			if c := re[1].(*expr.Cmp); c.Data[0] != byte(tt.dynamic.L3Proto) {
+				t.Errorf("Test \"%s\" failed, nfproto %d does not match expected %d", tt.name, c.Data[0], tt.dynamic.L3Proto)
+			}
+			re = re[2:]
+		}
+		p, ok := re[0].(*expr.Payload)
+		if !ok || p.Offset != tt.offset || p.Len != tt.length {
+			t.Errorf("Test \"%s\" failed, expression %+v does not load %d bytes at offset %d", tt.name, re[0], tt.length, tt.offset)
+		}
+	}
+}
+
+func TestDynamicOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		dynamic *Dynamic
+		last    bool
+	}{
+		{
+			name:    "Dynamic set without stateful expressions follows the action",
+			dynamic: &Dynamic{Match: MatchTypeL3Src, Op: unix.NFT_DYNSET_OP_ADD, SetRef: &SetRef{Name: "set-1"}},
+			last:    true,
+		},
+		{
+			name: "Dynamic set with stateful expressions precedes the action",
+			dynamic: &Dynamic{Match: MatchTypeL3Src, Op: unix.NFT_DYNSET_OP_UPDATE, SetRef: &SetRef{Name: "set-1"},
+				Counter: &Counter{}},
+			last: false,
+		},
+	}
+	nfr := &nfRules{conn: &setsConn{}, table: &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv4}}
+	for _, tt := range tests {
+		rr, err := nfr.buildRule(&Rule{Dynamic: tt.dynamic, Action: setActionVerdict(t, NFT_ACCEPT)})
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		exprs := rr.rule.Exprs
+		_, last := exprs[len(exprs)-1].(*expr.Dynset)
+		if last != tt.last {
+			t.Errorf("Test \"%s\" failed, unexpected order of expressions %+v", tt.name, exprs)
+		}
+	}
+}
+
+func TestSetQueue(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   *QueueAttributes
+		queue   *queue
+		success bool
+	}{
+		{
+			name:    "Single queue",
+			attrs:   &QueueAttributes{Num: [2]uint16{3, 0}},
+			queue:   &queue{num: 3, total: 1},
+			success: true,
+		},
+		{
+			name:    "Range of queues with bypass and fanout",
+			attrs:   &QueueAttributes{Num: [2]uint16{0, 3}, Bypass: true, Fanout: true},
+			queue:   &queue{num: 0, total: 4, flags: expr.QueueFlagBypass | expr.QueueFlagFanout},
+			success: true,
+		},
+		{
+			name:    "Invalid range of queues",
+			attrs:   &QueueAttributes{Num: [2]uint16{5, 2}},
+			success: false,
+		},
+		{
+			name:    "Largest range of queues",
+			attrs:   &QueueAttributes{Num: [2]uint16{1, 65535}},
+			queue:   &queue{num: 1, total: 65535},
+			success: true,
+		},
+		{
+			name:    "Range of all queues",
+			attrs:   &QueueAttributes{Num: [2]uint16{0, 65535}},
+			success: false,
+		},
+		{
+			name:    "Nil attributes",
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		ra, err := SetQueue(tt.attrs)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if *ra.queue != *tt.queue {
+			t.Errorf("Test \"%s\" failed, queue is %+v but supposed to be %+v", tt.name, *ra.queue, *tt.queue)
+		}
+	}
+}
+
+func TestDupFamily(t *testing.T) {
+	addr4, _ := NewIPAddr("192.0.2.10")
+	addr6, _ := NewIPAddr("2001:db8::10")
+	dev := binaryutil.NativeEndian.PutUint32(2)
+	tests := []struct {
+		name    string
+		family  nftables.TableFamily
+		dup     *dup
+		exprs   []expr.Any
+		success bool
+	}{
+		{
+			name:   "ipv4 address",
+			family: nftables.TableFamilyIPv4,
+			dup:    &dup{addr: addr4},
+			exprs: []expr.Any{
+				&expr.Immediate{Register: 1, Data: []byte{192, 0, 2, 10}},
+				&expr.Dup{RegAddr: 1},
+			},
+			success: true,
+		},
+		{
+			name:   "ipv6 address and device",
+			family: nftables.TableFamilyIPv6,
+			dup:    &dup{addr: addr6, dev: 2},
+			exprs: []expr.Any{
+				&expr.Immediate{Register: 1, Data: addr6.IP.To16()},
+				&expr.Immediate{Register: 2, Data: dev},
+				&expr.Dup{RegAddr: 1, RegDev: 2, IsRegDevSet: true},
+			},
+			success: true,
+		},
+		{
+			name:   "netdev device",
+			family: nftables.TableFamilyNetdev,
+			dup:    &dup{dev: 2},
+			exprs: []expr.Any{
+				&expr.Immediate{Register: 1, Data: dev},
+				&expr.Dup{RegDev: 1, IsRegDevSet: true},
+			},
+			success: true,
+		},
+		{
+			name:    "netdev address",
+			family:  nftables.TableFamilyNetdev,
+			dup:     &dup{addr: addr4, dev: 2},
+			success: false,
+		},
+		{
+			name:    "ipv4 address in ipv6 table",
+			family:  nftables.TableFamilyIPv6,
+			dup:     &dup{addr: addr4},
+			success: false,
+		},
+		{
+			name:    "inet table",
+			family:  nftables.TableFamilyINet,
+			dup:     &dup{addr: addr4},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		re, err := getExprForDup(tt.family, tt.dup)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(re, tt.exprs) {
+			t.Errorf("Test \"%s\" failed, expressions %+v do not match %+v", tt.name, re, tt.exprs)
+		}
+	}
+}
+
+func TestTProxy(t *testing.T) {
+	addr4, _ := NewIPAddr("127.0.0.1")
+	addr6, _ := NewIPAddr("::1")
+	tests := []struct {
+		name    string
+		family  nftables.TableFamily
+		tproxy  *tproxy
+		tp      *expr.TProxy
+		exprs   int
+		success bool
+	}{
+		{
+			name:    "ipv4 port",
+			family:  nftables.TableFamilyIPv4,
+			tproxy:  &tproxy{port: 15001},
+			tp:      &expr.TProxy{Family: byte(nftables.TableFamilyIPv4), TableFamily: byte(nftables.TableFamilyIPv4), RegPort: 1},
+			exprs:   2,
+			success: true,
+		},
+		{
+			name:    "inet port",
+			family:  nftables.TableFamilyINet,
+			tproxy:  &tproxy{port: 15001},
+			tp:      &expr.TProxy{Family: byte(nftables.TableFamilyUnspecified), TableFamily: byte(nftables.TableFamilyINet), RegPort: 1},
+			exprs:   2,
+			success: true,
+		},
+		{
+			name:    "ipv6 address and port",
+			family:  nftables.TableFamilyIPv6,
+			tproxy:  &tproxy{addr: addr6, port: 15001},
+			tp:      &expr.TProxy{Family: byte(nftables.TableFamilyIPv6), TableFamily: byte(nftables.TableFamilyIPv6), RegAddr: 1, RegPort: 2},
+			exprs:   3,
+			success: true,
+		},
+		{
+			name:    "inet ipv4 address of transparent socket with mark",
+			family:  nftables.TableFamilyINet,
+			tproxy:  &tproxy{addr: addr4, port: 15001, transparent: true, mark: &MetaMark{Set: true, Value: 1}},
+			tp:      &expr.TProxy{Family: byte(nftables.TableFamilyIPv4), TableFamily: byte(nftables.TableFamilyINet), RegAddr: 1, RegPort: 2},
+			exprs:   9,
+			success: true,
+		},
+		{
+			name:    "inet ipv6 address",
+			family:  nftables.TableFamilyINet,
+			tproxy:  &tproxy{addr: addr6, port: 15001},
+			tp:      &expr.TProxy{Family: byte(nftables.TableFamilyIPv6), TableFamily: byte(nftables.TableFamilyINet), RegAddr: 1, RegPort: 2},
+			exprs:   5,
+			success: true,
+		},
+		{
+			name:    "ipv4 address in ipv6 table",
+			family:  nftables.TableFamilyIPv6,
+			tproxy:  &tproxy{addr: addr4, port: 15001},
+			success: false,
+		},
+		{
+			name:    "bridge table",
+			family:  nftables.TableFamilyBridge,
+			tproxy:  &tproxy{port: 15001},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		re, err := getExprForTProxy(tt.family, tt.tproxy)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(re) != tt.exprs {
+			t.Errorf("Test \"%s\" failed, number of expressions is %d but supposed to be %d", tt.name, len(re), tt.exprs)
+			continue
+		}
+		for _, e := range re {
+			tp, ok := e.(*expr.TProxy)
+			if !ok {
+				continue
+			}
+			if !reflect.DeepEqual(tp, tt.tp) {
+				t.Errorf("Test \"%s\" failed, tproxy expression %+v does not match expected %+v", tt.name, tp, tt.tp)
+			}
+		}
+	}
+}
+
+func TestSocket(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "system.slice", "nginx.service"), 0755); err != nil {
+		t.Fatalf("failed to create cgroup directory with error: %+v", err)
+	}
+	var st unix.Stat_t
+	if err := unix.Stat(filepath.Join(root, "system.slice", "nginx.service"), &st); err != nil {
+		t.Fatalf("failed to stat cgroup directory with error: %+v", err)
+	}
+	defer func(r string) { cgroupv2Root = r }(cgroupv2Root)
+	cgroupv2Root = root
+	transparent := true
+	tests := []struct {
+		name    string
+		family  nftables.TableFamily
+		socket  *Socket
+		exprs   int
+		level   uint32
+		id      uint64
+		success bool
+	}{
+		{
+			name:    "transparent and wildcard",
+			family:  nftables.TableFamilyIPv4,
+			socket:  &Socket{Transparent: &transparent, Wildcard: &transparent},
+			exprs:   4,
+			success: true,
+		},
+		{
+			name:    "mark greater than",
+			family:  nftables.TableFamilyIPv6,
+			socket:  &Socket{Mark: &HeaderField{Value: 0x10, RelOp: GT}},
+			exprs:   3,
+			success: true,
+		},
+		{
+			name:    "cgroupv2 path",
+			family:  nftables.TableFamilyINet,
+			socket:  &Socket{Cgroupv2: &SocketCgroup{Path: "/system.slice/nginx.service/"}},
+			exprs:   2,
+			level:   2,
+			id:      st.Ino,
+			success: true,
+		},
+		{
+			name:    "cgroupv2 id",
+			family:  nftables.TableFamilyINet,
+			socket:  &Socket{Cgroupv2: &SocketCgroup{ID: 8001, Level: 3}},
+			exprs:   2,
+			level:   3,
+			id:      8001,
+			success: true,
+		},
+		{
+			name:    "cgroupv2 id without level",
+			family:  nftables.TableFamilyINet,
+			socket:  &Socket{Cgroupv2: &SocketCgroup{ID: 8001}},
+			success: false,
+		},
+		{
+			name:    "cgroupv2 root",
+			family:  nftables.TableFamilyINet,
+			socket:  &Socket{Cgroupv2: &SocketCgroup{Path: "/"}},
+			success: false,
+		},
+		{
+			name:    "cgroupv2 missing path",
+			family:  nftables.TableFamilyINet,
+			socket:  &Socket{Cgroupv2: &SocketCgroup{Path: "system.slice/sshd.service"}},
+			success: false,
+		},
+		{
+			name:    "no key",
+			family:  nftables.TableFamilyIPv4,
+			socket:  &Socket{},
+			success: false,
+		},
+		{
+			name:    "bridge table",
+			family:  nftables.TableFamilyBridge,
+			socket:  &Socket{Transparent: &transparent},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		re, err := getExprForSocket(tt.family, tt.socket)
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(re) != tt.exprs {
+			t.Errorf("Test \"%s\" failed, number of expressions is %d but supposed to be %d", tt.name, len(re), tt.exprs)
+			continue
+		}
+		if tt.socket.Cgroupv2 == nil {
+			continue
+		}
+		if s := re[0].(*expr.Socket); s.Level != tt.level {
+			t.Errorf("Test \"%s\" failed, level %d does not match expected %d", tt.name, s.Level, tt.level)
+		}
+		if id := binaryutil.NativeEndian.Uint64(re[1].(*expr.Cmp).Data); id != tt.id {
+			t.Errorf("Test \"%s\" failed, cgroup id %d does not match expected %d", tt.name, id, tt.id)
+		}
+	}
+}
+
+func TestMetaSet(t *testing.T) {
+	ref := &SetRef{Name: "classes", ID: 1, IsMap: true}
+	tests := []struct {
+		name    string
+		family  nftables.TableFamily
+		set     MetaSet
+		exprs   int
+		success bool
+	}{
+		{
+			name:    "priority",
+			family:  nftables.TableFamilyIPv4,
+			set:     MetaSet{Key: unix.NFT_META_PRIORITY, Value: TCHandle(1, 0x10)},
+			exprs:   2,
+			success: true,
+		},
+		{
+			name:   "priority by map of ipv6 destination",
+			family: nftables.TableFamilyINet,
+			set: MetaSet{Key: unix.NFT_META_PRIORITY, Map: &MetaSetMap{
+				Key:    []*ConcatElement{{EType: nftables.TypeIP6Addr}},
+				SetRef: ref,
+			}},
+			exprs:   5,
+			success: true,
+		},
+		{
+			name:    "nftrace",
+			family:  nftables.TableFamilyIPv6,
+			set:     MetaSet{Key: unix.NFT_META_NFTRACE, Value: []byte{1}},
+			exprs:   2,
+			success: true,
+		},
+		{
+			name:    "pkttype",
+			family:  nftables.TableFamilyNetdev,
+			set:     MetaSet{Key: unix.NFT_META_PKTTYPE, Value: []byte{unix.PACKET_HOST}},
+			exprs:   2,
+			success: true,
+		},
+		{
+			name:    "secmark",
+			family:  nftables.TableFamilyINet,
+			set:     MetaSet{Key: unix.NFT_META_SECMARK, Object: "sshtag"},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "secmark by value",
+			family:  nftables.TableFamilyINet,
+			set:     MetaSet{Key: unix.NFT_META_SECMARK, Value: []byte{1, 0, 0, 0}},
+			success: false,
+		},
+		{
+			name:    "priority by object",
+			family:  nftables.TableFamilyIPv4,
+			set:     MetaSet{Key: unix.NFT_META_PRIORITY, Object: "sshtag"},
+			success: false,
+		},
+		{
+			name:    "priority of invalid length",
+			family:  nftables.TableFamilyIPv4,
+			set:     MetaSet{Key: unix.NFT_META_PRIORITY, Value: []byte{1}},
+			success: false,
+		},
+		{
+			name:    "priority by value and map",
+			family:  nftables.TableFamilyIPv4,
+			set:     MetaSet{Key: unix.NFT_META_PRIORITY, Value: TCHandle(1, 0x10), Map: &MetaSetMap{SetRef: ref}},
+			success: false,
+		},
+		{
+			name:    "map without key",
+			family:  nftables.TableFamilyIPv4,
+			set:     MetaSet{Key: unix.NFT_META_PRIORITY, Map: &MetaSetMap{SetRef: ref}},
+			success: false,
+		},
+		{
+			name:    "unsupported key",
+			family:  nftables.TableFamilyIPv4,
+			set:     MetaSet{Key: unix.NFT_META_LEN, Value: []byte{1, 0, 0, 0}},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		re, err := getExprForMetaSet(tt.family, []MetaSet{tt.set})
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(re) != tt.exprs {
+			t.Errorf("Test \"%s\" failed, number of expressions is %d but supposed to be %d", tt.name, len(re), tt.exprs)
+			continue
+		}
+		if tt.set.Object != "" {
+			continue
+		}
+		if m, ok := re[len(re)-1].(*expr.Meta); !ok || !m.SourceRegister || m.Key != expr.MetaKey(tt.set.Key) {
+			t.Errorf("Test \"%s\" failed, last expression %+v does not set meta key %d", tt.name, re[len(re)-1], tt.set.Key)
+		}
+	}
+}
+
+func TestReject(t *testing.T) {
+	tcp := &L4Rule{L4Proto: unix.IPPROTO_TCP, Dst: &Port{List: SetPortList([]int{22})}}
+	tests := []struct {
+		name    string
+		family  nftables.TableFamily
+		action  func() (*RuleAction, error)
+		l4      *L4Rule
+		reject  *expr.Reject
+		exprs   int
+		success bool
+	}{
+		{
+			name:    "TCP reset after tcp match",
+			family:  nftables.TableFamilyIPv4,
+			action:  SetRejectTCPReset,
+			l4:      tcp,
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_TCP_RST},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "TCP reset without tcp match",
+			family:  nftables.TableFamilyIPv4,
+			action:  SetRejectTCPReset,
+			success: false,
+		},
+		{
+			name:    "ICMPx in ip6 table",
+			family:  nftables.TableFamilyIPv6,
+			action:  func() (*RuleAction, error) { return SetRejectICMPx(ICMPxRejectHostUnreachable) },
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_ICMP_UNREACH, Code: uint8(ICMPv6RejectAddrUnreachable)},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "ICMPx in inet table",
+			family:  nftables.TableFamilyINet,
+			action:  func() (*RuleAction, error) { return SetRejectICMPx(ICMPxRejectAdminProhibited) },
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_ICMPX_UNREACH, Code: unix.NFT_REJECT_ICMPX_ADMIN_PROHIBITED},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "ICMP in inet table",
+			family:  nftables.TableFamilyINet,
+			action:  func() (*RuleAction, error) { return SetRejectICMP(ICMPRejectHostProhibited) },
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_ICMP_UNREACH, Code: uint8(ICMPRejectHostProhibited)},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "ICMPv6 in ip table",
+			family:  nftables.TableFamilyIPv4,
+			action:  func() (*RuleAction, error) { return SetRejectICMPv6(ICMPv6RejectPortUnreachable) },
+			success: false,
+		},
+		{
+			name:    "Raw ICMP in inet table",
+			family:  nftables.TableFamilyINet,
+			action:  func() (*RuleAction, error) { return SetReject(unix.NFT_REJECT_ICMP_UNREACH, 3) },
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_ICMP_UNREACH, Code: 3},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "Raw ICMP code out of icmpv6 range in ip6 table",
+			family:  nftables.TableFamilyIPv6,
+			action:  func() (*RuleAction, error) { return SetReject(unix.NFT_REJECT_ICMP_UNREACH, 13) },
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_ICMP_UNREACH, Code: 13},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "Raw TCP reset without tcp match",
+			family:  nftables.TableFamilyIPv4,
+			action:  func() (*RuleAction, error) { return SetReject(unix.NFT_REJECT_TCP_RST, 0) },
+			reject:  &expr.Reject{Type: unix.NFT_REJECT_TCP_RST},
+			exprs:   1,
+			success: true,
+		},
+		{
+			name:    "Reject in arp table",
+			family:  nftables.TableFamilyARP,
+			action:  func() (*RuleAction, error) { return SetRejectICMPx(ICMPxRejectPortUnreachable) },
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		ra, err := tt.action()
+		if err != nil {
+			t.Errorf("Test \"%s\" failed to build action with error: \"%+v\"", tt.name, err)
+			continue
+		}
+		re, err := getExprForReject(tt.family, &Rule{L4: tt.l4, Action: ra})
+		if !tt.success {
+			if err == nil {
+				t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(re) != tt.exprs {
+			t.Errorf("Test \"%s\" failed, number of expressions is %d but supposed to be %d", tt.name, len(re), tt.exprs)
+			continue
+		}
+		if r, ok := re[len(re)-1].(*expr.Reject); !ok || *r != *tt.reject {
+			t.Errorf("Test \"%s\" failed, reject expression is %+v but supposed to be %+v", tt.name, re[len(re)-1], tt.reject)
+		}
+	}
+}
+
+func TestSetRejectCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		action func() (*RuleAction, error)
+	}{
+		{
+			name:   "ICMP code out of range",
+			action: func() (*RuleAction, error) { return SetRejectICMP(icmpRejectCodeMax + 1) },
+		},
+		{
+			name:   "ICMPv6 code out of range",
+			action: func() (*RuleAction, error) { return SetRejectICMPv6(ICMPv6RejectRejectRoute + 1) },
+		},
+		{
+			name:   "ICMPx code out of range",
+			action: func() (*RuleAction, error) { return SetRejectICMPx(unix.NFT_REJECT_ICMPX_MAX + 1) },
+		},
+	}
+	for _, tt := range tests {
+		if _, err := tt.action(); err == nil {
+			t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+		}
+	}
+	// SetReject does not validate its values
+	if _, err := SetReject(3, 16); err != nil {
+		t.Errorf("Test \"Raw reject\" failed with error: \"%+v\" but supposed to succeed", err)
+	}
+}
+
+func TestRejectGuardOrder(t *testing.T) {
+	nfr := &nfRules{conn: &setsConn{}, table: &nftables.Table{Name: "table-1", Family: nftables.TableFamilyINet}}
+	ra, err := SetRejectICMPv6(ICMPv6RejectAdminProhibited)
+	if err != nil {
+		t.Fatalf("failed to build action with error: %+v", err)
+	}
+	rr, err := nfr.buildRule(&Rule{
+		Counter: &Counter{},
+		Limit:   &Limit{Rate: 10, Unit: LimitPerSecond},
+		Log:     &Log{Key: unix.NFTA_LOG_PREFIX, Value: []byte("reject")},
+		Action:  ra,
+	})
+	if err != nil {
+		t.Fatalf("failed to build rule with error: %+v", err)
+	}
+	// Packets of the other family must not be counted, limited nor logged
+	expect := []expr.Any{
+		&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{unix.NFPROTO_IPV6}},
+	}
+	exprs := rr.rule.Exprs
+	if len(exprs) < 3 || !reflect.DeepEqual(exprs[:2], expect) {
+		t.Fatalf("rule expressions %+v do not start with %+v", exprs, expect)
+	}
+	if _, ok := exprs[2].(*expr.Counter); !ok {
+		t.Fatalf("third expression %+v is not counter", exprs[2])
+	}
+	if _, ok := exprs[len(exprs)-1].(*expr.Reject); !ok {
+		t.Fatalf("last expression %+v is not reject", exprs[len(exprs)-1])
+	}
+}
+
+func TestSetNATMap(t *testing.T) {
+	addr, _ := NewIPAddr("192.0.2.1")
+	ref := &SetRef{Name: "map-1", IsMap: true}
+	key := []*ConcatElement{{EType: nftables.TypeIPAddr, ESource: true}}
+	tests := []struct {
+		name      string
+		attrs     *NATAttributes
+		success   bool
+		family    nftables.TableFamily
+		registers []uint32
+	}{
+		{
+			name:    "Map of addresses",
+			attrs:   &NATAttributes{Map: &NATMap{Key: key, SetRef: ref, DataType: nftables.TypeIPAddr}},
+			success: true,
+		},
+		{
+			name: "IPv6 map by concatenated key",
+			attrs: &NATAttributes{Map: &NATMap{
+				Key: []*ConcatElement{
+					{EType: nftables.TypeIP6Addr, ESource: true},
+					{EType: nftables.TypeInetService},
+				},
+				SetRef:   ref,
+				DataType: nftables.MustConcatSetType(nftables.TypeIP6Addr, nftables.TypeInetService),
+			}},
+			success: true,
+			family:  nftables.TableFamilyIPv6,
+			// The port follows the address in NFT_REG32_04
+			registers: []uint32{1, 12},
+		},
+		{
+			name: "Pool map of addresses and ports",
+			attrs: &NATAttributes{Map: &NATMap{Modulus: 4, SetRef: ref,
+				DataType: nftables.MustConcatSetType(nftables.TypeIP6Addr, nftables.TypeInetService)}},
+			success: true,
+		},
+		{
+			name:    "Map without key and modulus",
+			attrs:   &NATAttributes{Map: &NATMap{SetRef: ref, DataType: nftables.TypeIPAddr}},
+			success: false,
+		},
+		{
+			name:    "Map with invalid data",
+			attrs:   &NATAttributes{Map: &NATMap{Key: key, SetRef: ref, DataType: nftables.TypeInetService}},
+			success: false,
+		},
+		{
+			name: "Map with port before address",
+			attrs: &NATAttributes{Map: &NATMap{Key: key, SetRef: ref,
+				DataType: nftables.MustConcatSetType(nftables.TypeInetService, nftables.TypeIPAddr)}},
+			success: false,
+		},
+		{
+			name:    "Pool combined with address",
+			attrs:   &NATAttributes{L3Addr: [2]*IPAddr{addr}, Pool: []*IPAddr{addr}},
+			success: false,
+		},
+		{
+			name:    "Pool combined with map",
+			attrs:   &NATAttributes{Pool: []*IPAddr{addr}, Map: &NATMap{Key: key, SetRef: ref, DataType: nftables.TypeIPAddr}},
+			success: false,
+		},
+	}
+	for _, tt := range tests {
+		ra, err := SetDNAT(tt.attrs)
+		if tt.success && err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+		}
+		if !tt.success && err == nil {
+			t.Errorf("Test \"%s\" succeeded but supposed to fail", tt.name)
+		}
+		if tt.registers == nil || err != nil {
+			continue
+		}
+		nfr := &nfRules{conn: &setsConn{}, table: &nftables.Table{Name: "table-1", Family: tt.family}}
+		re, err := getExprForNATMap(nfr, ra.nat)
+		if err != nil {
+			t.Errorf("Test \"%s\" failed to build nat map with error: \"%+v\"", tt.name, err)
+			continue
+		}
+		var registers []uint32
+		for _, e := range re {
+			if e, ok := e.(*expr.Payload); ok {
+				registers = append(registers, e.DestRegister)
+			}
+		}
+		if !reflect.DeepEqual(registers, tt.registers) {
+			t.Errorf("Test \"%s\" failed, registers of key %v do not match %v", tt.name, registers, tt.registers)
+		}
+	}
+}
+
+func TestLoadbalanceBuckets(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		hash    *LoadbalanceHash
+		buckets []string
+	}{
+		{
+			name:    "Equal chains",
+			buckets: []string{"chain-1", "chain-2", "chain-3"},
+		},
+		{
+			name:    "Weights reduced by divisor",
+			weights: []int{4, 2, 2},
+			buckets: []string{"chain-1", "chain-1", "chain-2", "chain-3"},
+		},
+		{
+			name:    "Weighted hash",
+			weights: []int{1, 1, 2},
+			hash:    &LoadbalanceHash{Fields: []*ConcatElement{{EType: nftables.TypeIPAddr, ESource: true}}, Seed: 1},
+			buckets: []string{"chain-1", "chain-2", "chain-3", "chain-3"},
+		},
+	}
+	for _, tt := range tests {
+		conn := &setsConn{}
+		nfr := &nfRules{conn: conn, table: &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv4}}
+		re, err := getExprForLoadbalance(nfr, &loadbalance{
+			chains:  []string{"chain-1", "chain-2", "chain-3"},
+			weights: tt.weights,
+			hash:    tt.hash,
+			mode:    unix.NFT_NG_INCREMENTAL,
+		})
+		if err != nil {
+			t.Errorf("Test \"%s\" failed with error: \"%+v\" but supposed to succeed", tt.name, err)
+			continue
+		}
+		if len(conn.elements) != len(tt.buckets) {
+			t.Errorf("Test \"%s\" failed, number of buckets is %d but supposed to be %d", tt.name, len(conn.elements), len(tt.buckets))
+			continue
+		}
+		for i, e := range conn.elements {
+			if e.VerdictData.Chain != tt.buckets[i] {
+				t.Errorf("Test \"%s\" failed, bucket %d is %s but supposed to be %s", tt.name, i, e.VerdictData.Chain, tt.buckets[i])
+			}
+		}
+		switch e := re[len(re)-2].(type) {
+		case *expr.Numgen:
+			if tt.hash != nil || e.Type != unix.NFT_NG_INCREMENTAL || e.Modulus != uint32(len(tt.buckets)) {
+				t.Errorf("Test \"%s\" failed, invalid numgen %+v", tt.name, e)
+			}
+		case *expr.Hash:
+			if tt.hash == nil || e.Seed != tt.hash.Seed || e.Modulus != uint32(len(tt.buckets)) {
+				t.Errorf("Test \"%s\" failed, invalid hash %+v", tt.name, e)
+			}
+		default:
+			t.Errorf("Test \"%s\" failed, unexpected expression %T", tt.name, e)
+		}
+	}
+}
+
+func TestLoadbalanceHashIPv6Registers(t *testing.T) {
+	conn := &setsConn{}
+	nfr := &nfRules{conn: conn, table: &nftables.Table{Name: "table-1", Family: nftables.TableFamilyIPv6}}
+	hash := &LoadbalanceHash{
+		Fields: []*ConcatElement{
+			{EType: nftables.TypeIP6Addr, ESource: true},
+			{EType: nftables.TypeIP6Addr},
+			{EType: nftables.TypeInetProto},
+			{EType: nftables.TypeInetService, ESource: true},
+			{EType: nftables.TypeInetService},
+		},
+	}
+	re, err := getExprForLoadbalance(nfr, &loadbalance{
+		chains: []string{"chain-1", "chain-2"},
+		hash:   hash,
+	})
+	if err != nil {
+		t.Fatalf("failed to build loadbalance with error: %+v", err)
+	}
+	// IPv6 addresses take 4 32-bit registers each, the first one is loaded into reg 1, the second one
+	// into NFT_REG32_04 and the following elements into the next 32-bit registers.
+	expect := []uint32{1, 12, 16, 17, 18}
+	var registers []uint32
+	for _, e := range re {
+		switch e := e.(type) {
+		case *expr.Payload:
+			registers = append(registers, e.DestRegister)
+		case *expr.Hash:
+			if e.SourceRegister != 1 || e.Length != 44 {
+				t.Errorf("invalid hash %+v, expected source register 1 and length 44", e)
+			}
+		}
+	}
+	if !reflect.DeepEqual(registers, expect) {
+		t.Fatalf("registers %v do not match %v", registers, expect)
+	}
+}
+
+// setsConn records elements of the last set added to the connection
+type setsConn struct {
+	NetNS
+	elements []nftables.SetElement
+}
+
+func (c *setsConn) AddSet(s *nftables.Set, elements []nftables.SetElement) error {
+	c.elements = elements
+	return nil
+}
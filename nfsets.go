@@ -20,6 +20,9 @@ type SetAttributes struct {
 	Timeout    time.Duration
 	// Interval flag must be set only when the set elements are ranges, address ranges or port ranges
 	Interval bool
+	// Dynamic flag must be set when the set is updated by Dynamic rules attaching stateful expressions
+	// to the set elements
+	Dynamic  bool
 	KeyType  nftables.SetDatatype
 	DataType nftables.SetDatatype
 }
@@ -75,12 +78,12 @@ func (nfs *nfSets) CreateSet(attrs *SetAttributes, elements []nftables.SetElemen
 	// TODO Add parameters validation
 	se := []nftables.SetElement{}
 	if attrs.Interval {
-		if attrs.KeyType == nftables.TypeIPAddr || attrs.KeyType == nftables.TypeIP6Addr {
-			if nfs.table.Family == nftables.TableFamilyIPv4 {
-				se = append(se, nftables.SetElement{Key: net.ParseIP("0.0.0.0").To4(), IntervalEnd: true})
-			} else {
-				se = append(se, nftables.SetElement{Key: net.ParseIP("::").To16(), IntervalEnd: true})
-			}
+		// Key type rather than table's family defines the address family, as inet table can carry both.
+		switch attrs.KeyType {
+		case nftables.TypeIPAddr:
+			se = append(se, nftables.SetElement{Key: net.ParseIP("0.0.0.0").To4(), IntervalEnd: true})
+		case nftables.TypeIP6Addr:
+			se = append(se, nftables.SetElement{Key: net.ParseIP("::").To16(), IntervalEnd: true})
 		}
 	}
 	s := &nftables.Set{
@@ -92,6 +95,7 @@ func (nfs *nfSets) CreateSet(attrs *SetAttributes, elements []nftables.SetElemen
 		Interval:   attrs.Interval,
 		IsMap:      attrs.IsMap,
 		HasTimeout: attrs.HasTimeout,
+		Dynamic:    attrs.Dynamic,
 		KeyType:    attrs.KeyType,
 		DataType:   attrs.DataType,
 	}
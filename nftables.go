@@ -42,6 +42,7 @@ type nfTable struct {
 	table *nftables.Table
 	ChainsInterface
 	SetsInterface
+	ObjectsInterface
 }
 
 // Tables returns methods available for managing nf tables
@@ -88,6 +89,19 @@ func (nft *nfTables) TableSets(name string, familyType nftables.TableFamily) (Se
 	return nil, fmt.Errorf("table %s of type %v does not exist", name, familyType)
 }
 
+// TableObjects returns Objects Interface for a specific table
+func (nft *nfTables) TableObjects(name string, familyType nftables.TableFamily) (ObjectsInterface, error) {
+	nft.Lock()
+	defer nft.Unlock()
+	// Check if nf table with the same family type and name  already exists
+	if t, ok := nft.tables[familyType][name]; ok {
+		return t.ObjectsInterface, nil
+
+	}
+
+	return nil, fmt.Errorf("table %s of type %v does not exist", name, familyType)
+}
+
 // Create appends a table into NF tables list
 func (nft *nfTables) Create(name string, familyType nftables.TableFamily) error {
 	nft.Lock()
@@ -102,8 +116,9 @@ func (nft *nfTables) create(name string, familyType nftables.TableFamily) *nfTab
 	if _, ok := nft.tables[familyType]; ok {
 		// Check if table  already exists
 		if _, ok := nft.tables[familyType][name]; ok {
-			// Check if table has ChainsInterface and SetsInterface instantiated
-			if nft.tables[familyType][name].ChainsInterface != nil && nft.tables[familyType][name].SetsInterface != nil {
+			// Check if table has ChainsInterface, SetsInterface and ObjectsInterface instantiated
+			if nft.tables[familyType][name].ChainsInterface != nil && nft.tables[familyType][name].SetsInterface != nil &&
+				nft.tables[familyType][name].ObjectsInterface != nil {
 				// Table already exists with proper interfaces, no need to do anything
 				return nft.tables[familyType][name]
 			}
@@ -118,9 +133,10 @@ func (nft *nfTables) create(name string, familyType nftables.TableFamily) *nfTab
 		Name:   name,
 	}
 	nft.tables[familyType][name] = &nfTable{
-		table:           t,
-		ChainsInterface: newChains(nft.conn, t),
-		SetsInterface:   newSets(nft.conn, t),
+		table:            t,
+		ChainsInterface:  newChains(nft.conn, t),
+		SetsInterface:    newSets(nft.conn, t),
+		ObjectsInterface: newObjects(nft.conn, t),
 	}
 
 	return nft.tables[familyType][name]
@@ -154,11 +170,14 @@ func (nft *nfTables) Delete(name string, familyType nftables.TableFamily) error
 	nft.Lock()
 	defer nft.Unlock()
 	// Check if nf table with the same family type and name  already exists
-	if _, ok := nft.tables[familyType][name]; ok {
+	_, ok := nft.tables[familyType][name]
+	if ok {
 		// Removing old table, at this point, this table should be removed from the kernel as well.
 		delete(nft.tables[familyType], name)
 	}
-	if nft.Tables().Exist(name, familyType) {
+	// The table from the store might not be programmed yet, but still pending in the connection's queue,
+	// it must be deleted as well.
+	if ok || nft.Tables().Exist(name, familyType) {
 		nft.conn.DelTable(&nftables.Table{
 			Name:   name,
 			Family: familyType,
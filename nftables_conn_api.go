@@ -2,6 +2,7 @@ package nftableslib
 
 import (
 	"github.com/google/nftables"
+	"github.com/mdlayher/netlink"
 )
 
 // NetNS defines interface needed to nf tables
@@ -27,3 +28,20 @@ type NetNS interface {
 	SetAddElements(*nftables.Set, []nftables.SetElement) error
 	SetDeleteElements(*nftables.Set, []nftables.SetElement) error
 }
+
+// ObjectsConn defines interface needed to operate with named stateful objects, it is optional,
+// ObjectFuncs fail when the connection does not implement it.
+type ObjectsConn interface {
+	AddObj(nftables.Obj) nftables.Obj
+	DeleteObject(nftables.Obj)
+	GetNamedObjects(*nftables.Table) ([]nftables.Obj, error)
+	ResetObject(nftables.Obj) (nftables.Obj, error)
+}
+
+// RulesResetConn defines interface needed to reset counters of rules, it is optional, ResetRules executes
+// NFT_MSG_GETRULE_RESET request and returns messages of reset rules. google/nftables does not offer rule's reset,
+// for *nftables.Conn the request is sent over a separate netlink connection to the connection's namespace,
+// other connections fail to reset counters when they do not implement it.
+type RulesResetConn interface {
+	ResetRules(req netlink.Message) ([]netlink.Message, error)
+}
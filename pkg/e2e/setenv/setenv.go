@@ -147,11 +147,11 @@ func newIPAddr(addr string) (*nftableslib.IPAddr, error) {
 		ones, _ := ipnet.Mask.Size()
 		mask := uint8(ones)
 		return &nftableslib.IPAddr{
-			&net.IPAddr{
+			IPAddr: &net.IPAddr{
 				IP: ip,
 			},
-			true,
-			&mask,
+			CIDR: true,
+			Mask: &mask,
 		}, nil
 	}
 	// Check if addr is just ip address in a non CIDR format
@@ -168,11 +168,11 @@ func newIPAddr(addr string) (*nftableslib.IPAddr, error) {
 		return nil, err
 	}
 	return &nftableslib.IPAddr{
-		&net.IPAddr{
+		IPAddr: &net.IPAddr{
 			IP: ip,
 		},
-		true,
-		&mask,
+		CIDR: true,
+		Mask: &mask,
 	}, nil
 }
 
@@ -265,6 +265,25 @@ func addVethToNS(ns1, ns2 netns.NsHandle, veth *netlink.Veth) (netlink.Link, net
 	return l1, l2, nil
 }
 
+// GetVethName returns the name of the veth interface of the p2p link in the namespace
+func GetVethName(ns netns.NsHandle) (string, error) {
+	nsh, err := netlink.NewHandleAt(ns)
+	if err != nil {
+		return "", fmt.Errorf("failure to get namespace's handle with error: %+v", err)
+	}
+	links, err := nsh.LinkList()
+	if err != nil {
+		return "", fmt.Errorf("failure to get a list of links from the namespace %s with error: %+v", ns.String(), err)
+	}
+	for _, link := range links {
+		if _, ok := link.(*netlink.Veth); ok {
+			return link.Attrs().Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("veth is not found in the namespace %s", ns.String())
+}
+
 func waitForLink(ns netns.NsHandle, linkName string) (netlink.Link, error) {
 	org, err := netns.Get()
 	if err != nil {
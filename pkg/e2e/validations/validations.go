@@ -304,6 +304,86 @@ func ICMPDropTestValidation(version nftables.TableFamily, ns []netns.NsHandle, i
 	return nil
 }
 
+// NetdevICMPDropValidation validation function for test: "Netdev ICMP Drop", ICMP packets are dropped by the chain
+// of netdev table bound to the veth interface of the destination namespace and to its loopback, first at ingress
+// and then at egress hook.
+func NetdevICMPDropValidation(version nftables.TableFamily, ns []netns.NsHandle, ip []*nftableslib.IPAddr) error {
+	dev, err := setenv.GetVethName(ns[1])
+	if err != nil {
+		return err
+	}
+	for _, hook := range []*nftables.ChainHook{nftables.ChainHookIngress, nftables.ChainHookEgress} {
+		if err := netdevICMPDrop(version, ns, ip, dev, hook); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func netdevICMPDrop(version nftables.TableFamily, ns []netns.NsHandle, ip []*nftableslib.IPAddr, dev string, hook *nftables.ChainHook) error {
+	proto := unix.IPPROTO_ICMP
+	if version == nftables.TableFamilyIPv6 {
+		proto = unix.IPPROTO_ICMPV6
+	}
+	accept := nftableslib.ChainPolicyAccept
+	drop, err := nftableslib.SetVerdict(nftableslib.NFT_DROP)
+	if err != nil {
+		return err
+	}
+	nfrules := []setenv.TestChain{
+		{
+			Name: "chain-1",
+			Attr: &nftableslib.ChainAttributes{
+				Type:     nftables.ChainTypeFilter,
+				Priority: nftables.ChainPriorityFilter,
+				Hook:     hook,
+				Devices:  []string{dev, "lo"},
+				Policy:   &accept,
+			},
+			Rules: []nftableslib.Rule{
+				{
+					L3: &nftableslib.L3Rule{
+						Protocol: nftableslib.L3Protocol(proto),
+					},
+					Action: drop,
+				},
+			},
+		},
+	}
+	if _, err := setenv.NFTablesSet(setenv.MakeTablesInterface(ns[1]), nftables.TableFamilyNetdev, nfrules, false, "netdev"); err != nil {
+		return err
+	}
+	if err := setenv.TestICMP(ns[0], version, ip[0], ip[1]); err == nil {
+		return fmt.Errorf("failed as the connectivity test supposed to fail, but succeeded")
+	}
+	// Per device chains are discovered by Sync, they must not be exposed as separate chains and must be removed
+	// along with the chain.
+	ti := setenv.MakeTablesInterface(ns[1])
+	if err := ti.Tables().Sync(nftables.TableFamilyNetdev); err != nil {
+		return fmt.Errorf("fail to Sync with error: %+v", err)
+	}
+	ci, err := ti.Tables().TableChains("netdev", nftables.TableFamilyNetdev)
+	if err != nil {
+		return err
+	}
+	chains, err := ci.Chains().Get()
+	if err != nil {
+		return err
+	}
+	if len(chains) != 1 || chains[0] != "chain-1" {
+		return fmt.Errorf("discovered chains %v do not match chain-1", chains)
+	}
+	if err := ci.Chains().DeleteImm("chain-1"); err != nil {
+		return fmt.Errorf("fail to delete chain-1 with error: %+v", err)
+	}
+	if err := setenv.TestICMP(ns[0], version, ip[0], ip[1]); err != nil {
+		return fmt.Errorf("connectivity test failed after removal of the chain with error: %+v", err)
+	}
+
+	return ti.Tables().DeleteImm("netdev", nftables.TableFamilyNetdev)
+}
+
 // SNATValidation validation function for test: "IPV4 SNAT"
 func getPacketFromDestination(version nftables.TableFamily, ns []netns.NsHandle, ip []*nftableslib.IPAddr,
 	proto int, srcPort, dstPort string) (net.Addr, error) {